@@ -1,16 +1,21 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/projectdiscovery/goflags"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/pdtm/internal/runner"
 )
 
 func main() {
+	// pdtm sets its config file explicitly, goflags must not move config
+	// directories it derives from the program name
+	goflags.DisableAutoConfigMigration = true
 	options := runner.ParseOptions()
 	pdtmRunner, err := runner.NewRunner(options)
 	if err != nil {
@@ -28,6 +33,13 @@ func main() {
 	}()
 
 	err = pdtmRunner.Run()
+	var exitErr *runner.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Err != nil {
+			gologger.Error().Msgf("Could not run pdtm: %s\n", exitErr.Err)
+		}
+		os.Exit(exitErr.Code)
+	}
 	if err != nil {
 		gologger.Fatal().Msgf("Could not run pdtm: %s\n", err)
 	}
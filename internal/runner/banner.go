@@ -1,8 +1,15 @@
 package runner
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/minio/selfupdate"
 	"github.com/projectdiscovery/gologger"
-	updateutils "github.com/projectdiscovery/utils/update"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	pdtmversion "github.com/projectdiscovery/pdtm/pkg/version"
 )
 
 const version = "v0.1.3"
@@ -22,10 +29,52 @@ func showBanner() {
 	gologger.Print().Msgf("\t\tprojectdiscovery.io\n\n")
 }
 
-// GetUpdateCallback returns a callback function that updates pdtm
-func GetUpdateCallback() func() {
-	return func() {
-		showBanner()
-		updateutils.GetUpdateToolCallback("pdtm", version)()
+// selfUpdate replaces the running pdtm binary with its latest release. The
+// release is fetched, verified and extracted like any other tool, through
+// the configured GitHub api and http client.
+func selfUpdate() error {
+	latest, err := pkg.GetLatestRelease(types.Tool{Name: "pdtm", Repo: "pdtm", InstallType: types.Binary})
+	if err != nil {
+		return err
+	}
+	if pdtmversion.Status(version, latest.Version) != types.StatusOutdated {
+		gologger.Info().Msgf("pdtm is already updated to latest version")
+		return nil
+	}
+	updateOptions := selfupdate.Options{}
+	if err := updateOptions.CheckPermissions(); err != nil {
+		return fmt.Errorf("insufficient permissions to replace pdtm: %w", err)
+	}
+
+	stagingDir, err := os.MkdirTemp("", "pdtm-self-update-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(stagingDir); err != nil {
+			gologger.Warning().Msgf("Error removing %s: %s", stagingDir, err)
+		}
+	}()
+	if err := pkg.Install(stagingDir, latest); err != nil {
+		return err
+	}
+	binaryPath, ok := path.ActiveExecutablePath(stagingDir, "pdtm")
+	if !ok {
+		return fmt.Errorf(types.ErrNoBinaryInArchive, "pdtm")
+	}
+	binary, err := os.Open(binaryPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = binary.Close()
+	}()
+	if err := selfupdate.Apply(binary, updateOptions); err != nil {
+		if rollbackErr := selfupdate.RollbackError(err); rollbackErr != nil {
+			return fmt.Errorf("%w, rollback failed: %s, please reinstall pdtm", err, rollbackErr)
+		}
+		return err
 	}
+	gologger.Info().Msgf("pdtm successfully updated %s -> v%s (latest)", version, latest.Version)
+	return nil
 }
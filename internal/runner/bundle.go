@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	pdtmversion "github.com/projectdiscovery/pdtm/pkg/version"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// createBundle writes the release assets of the bundled tools for the
+// requested platforms to the bundle file
+func (r *Runner) createBundle(toolList []types.Tool) error {
+	platforms, err := bundlePlatforms(r.options.BundlePlatforms)
+	if err != nil {
+		return err
+	}
+
+	toolArgs := r.options.BundleTools
+	if len(toolArgs) == 0 {
+		for _, tool := range toolList {
+			toolArgs = append(toolArgs, tool.Name)
+		}
+	}
+	var tools []types.Tool
+	for _, toolArg := range toolArgs {
+		toolName, version := splitToolVersion(toolArg)
+		tool, _, err := lookupTool(toolList, toolName)
+		if err != nil {
+			return fmt.Errorf("%s: %w", toolName, err)
+		}
+		if tool, err = r.resolveTool(tool, version); err != nil {
+			return fmt.Errorf("%s: could not find release %s: %w", toolName, version, err)
+		}
+		tools = append(tools, tool)
+	}
+
+	// the bundle is written next to its destination and only moved in once complete
+	file := r.options.BundleCreate
+	tmpFile, err := os.CreateTemp(filepath.Dir(file), ".pdtm-bundle-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+	if err := pkg.CreateBundle(tmpFile, tools, platforms); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile.Name(), file); err != nil {
+		return err
+	}
+	gologger.Info().Msgf("created bundle %s for %s", file, formatPlatforms(platforms))
+	return nil
+}
+
+// bundlePlatforms parses the os/arch platforms to bundle, the current
+// platform when none is given
+func bundlePlatforms(values []string) ([]pkg.Platform, error) {
+	if len(values) == 0 {
+		return []pkg.Platform{pkg.CurrentPlatform()}, nil
+	}
+	platforms := make([]pkg.Platform, 0, len(values))
+	for _, value := range values {
+		platform, err := pkg.ParsePlatform(value)
+		if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, platform)
+	}
+	return platforms, nil
+}
+
+func formatPlatforms(platforms []pkg.Platform) string {
+	names := make([]string, 0, len(platforms))
+	for _, platform := range platforms {
+		names = append(names, platform.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// installBundle installs the bundled tools from the bundle file. Nothing is
+// fetched over the network, the cache is seeded with the bundled tool list
+// when there is none yet.
+func (r *Runner) installBundle() error {
+	bundle, err := pkg.OpenBundle(r.options.BundleInstall)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := bundle.Close(); err != nil {
+			gologger.Warning().Msgf("could not remove unpacked bundle: %s", err)
+		}
+	}()
+
+	if !fileutil.FileExists(cacheFile) {
+		if err := UpdateCache(bundle.Tools); err != nil {
+			gologger.Warning().Msgf("%s\n", err)
+		}
+	}
+
+	toolArgs := r.options.BundleTools
+	if len(toolArgs) == 0 {
+		for _, tool := range bundle.Tools {
+			toolArgs = append(toolArgs, tool.Name)
+		}
+	}
+	results := r.runConcurrently(toolArgs, func(toolArg string) toolResult {
+		return r.installBundledTool(bundle, toolArg)
+	})
+	if r.jsonOutput() {
+		if err := writeRecords(results, r.options.JSONL); err != nil {
+			return err
+		}
+	} else {
+		printSummary(results)
+	}
+	return failureExit(results)
+}
+
+// installBundledTool installs a single bundled tool given as name or name@version
+func (r *Runner) installBundledTool(bundle *pkg.Bundle, toolArg string) toolResult {
+	toolName, version := splitToolVersion(toolArg)
+	result := toolResult{Tool: toolName, Operation: operationInstall}
+	if !path.IsSubPath(homeDir, r.options.Path) {
+		gologger.Error().Msgf("skipping install outside home folder: %s", toolName)
+		return result.skipped("outside home folder")
+	}
+	tool, unlisted, err := lookupTool(bundle.Tools, toolName)
+	if err == nil && (unlisted || (version != "" && !pdtmversion.Equal(version, tool.Version))) {
+		err = fmt.Errorf("%s is not in the bundle", toolArg)
+	}
+	if err != nil {
+		gologger.Error().Msgf("error while installing %s: %s", toolName, err)
+		return result.failed(err)
+	}
+	result.Version = tool.Version
+
+	if err := bundle.Install(r.options.Path, tool); err != nil {
+		if errors.Is(err, types.ErrIsUpToDate) || errors.Is(err, types.ErrIsNewer) {
+			gologger.Info().Msgf("%s: %s", tool.Name, err)
+			return result.skipped(err.Error())
+		}
+		gologger.Error().Msgf("error while installing %s: %s", tool.Name, err)
+		return result.failed(err)
+	}
+	r.pin(tool.Name, version)
+	return result.succeeded()
+}
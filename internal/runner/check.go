@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// Exit codes returned by check mode, from least to most severe
+const (
+	ExitCodeOK       = 0
+	ExitCodeError    = 1
+	ExitCodeOutdated = 2
+	ExitCodeMissing  = 3
+)
+
+// ExitError carries the exit code pdtm should terminate with
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit code %d", e.Code)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// isCheck reports whether check mode was requested
+func (r *Runner) isCheck() bool {
+	return len(r.options.Check) > 0 || r.options.CheckAll
+}
+
+// check compares the installed tools with the tool list and returns an
+// ExitError describing the most severe problem found. It never modifies
+// the binary path, $PATH or the cache.
+func (r *Runner) check(toolList []types.Tool) error {
+	toolNames := r.options.Check
+	if r.options.CheckAll {
+		toolNames = nil
+		for _, tool := range r.selectedTools(toolList) {
+			toolNames = append(toolNames, tool.Name)
+		}
+	}
+
+	var hasError, hasMissing, hasOutdated bool
+	var records []toolRecord
+	for _, toolName := range toolNames {
+		tool, unlisted, err := lookupTool(toolList, toolName)
+		if err == nil && unlisted {
+			err = errNotInList
+		}
+		if err != nil {
+			gologger.Error().Msgf("%s: %s", toolName, err)
+			hasError = true
+			continue
+		}
+		// pinned tools are expected at their pinned version
+		if version, ok := r.pins.Get(tool.Name); ok {
+			tool.Version = version
+		}
+
+		record := r.newToolRecord(tool)
+		switch record.Status {
+		case types.StatusLatest, types.StatusNewer:
+			gologger.Info().Msgf("%s: %s is %s", tool.Name, record.InstalledVersion, record.Status)
+		case types.StatusOutdated:
+			gologger.Warning().Msgf("%s: %s is %s, expected %s", tool.Name, record.InstalledVersion, record.Status, tool.Version)
+			hasOutdated = true
+		case types.StatusNotInstalled:
+			if _, exists := path.GetExecutablePath(r.options.Path, tool.Name); exists {
+				gologger.Error().Msgf("%s: could not determine installed version", tool.Name)
+				hasError = true
+				continue
+			}
+			gologger.Warning().Msgf("%s: %s", tool.Name, record.Status)
+			hasMissing = true
+		case types.StatusNotSupported:
+			gologger.Verbose().Msgf("%s: %s on this platform, skipping", tool.Name, record.Status)
+		}
+		records = append(records, record)
+	}
+
+	if r.jsonOutput() {
+		if err := writeRecords(records, r.options.JSONL); err != nil {
+			return &ExitError{Code: ExitCodeError, Err: err}
+		}
+	}
+
+	switch {
+	case hasError:
+		return &ExitError{Code: ExitCodeError}
+	case hasMissing:
+		return &ExitError{Code: ExitCodeMissing}
+	case hasOutdated:
+		return &ExitError{Code: ExitCodeOutdated}
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package runner
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/goflags"
+	"github.com/projectdiscovery/pdtm/pkg"
+)
+
+// command is a pdtm subcommand with its own flags. The legacy flags stay
+// available when no command is given.
+type command struct {
+	// name is one or more words, e.g. "bundle create"
+	name string
+	// args describes the positional arguments in the usage
+	args        string
+	description string
+	// flags creates the groups of the flags specific to the command
+	flags func(flagSet *goflags.FlagSet, options *Options)
+	// apply sets the options selected by the positional arguments
+	apply func(options *Options, args []string) error
+}
+
+// trim returns the arguments following the name of c in args
+func (c command) trim(args []string) []string {
+	return args[len(strings.Fields(c.name)):]
+}
+
+var commands = []command{
+	{
+		name:        "install",
+		args:        "<project[@version]>...",
+		description: "install single or multiple projects given as name or owner/repo, name@version installs and pins a specific version",
+		flags: func(flagSet *goflags.FlagSet, options *Options) {
+			flagSet.CreateGroup("install", "Install",
+				flagSet.BoolVarP(&options.InstallAll, "all", "a", false, "install all the projects of the tool set"),
+				flagSet.BoolVarP(&options.SetPath, "install-path", "ip", false, "append path to PATH environment variables"),
+				flagSet.BoolVarP(&options.SetGoPath, "install-go-path", "igp", false, "append GOBIN/GOPATH to PATH environment variables"),
+				flagSet.IntVarP(&options.KeepVersions, "keep-versions", "kv", pkg.DefaultRetention, "number of inactive versions to keep per project for rollbacks (0 to keep none)"),
+				flagSet.BoolVarP(&options.SkipChecksum, "skip-checksum", "sc", false, "install projectdiscovery releases without a checksums file unverified"),
+			)
+		},
+		apply: func(options *Options, args []string) error {
+			options.Install = projectArgs(args)
+			return requireProjects("install", options.Install, options.InstallAll)
+		},
+	},
+	{
+		name:        "update",
+		args:        "<project[@version]>...",
+		description: "update single or multiple projects, name@version updates and pins a specific version",
+		flags: func(flagSet *goflags.FlagSet, options *Options) {
+			flagSet.CreateGroup("update", "Update",
+				flagSet.BoolVarP(&options.UpdateAll, "all", "a", false, "update all the projects of the tool set (pinned projects stay on their pinned version)"),
+				flagSet.IntVarP(&options.KeepVersions, "keep-versions", "kv", pkg.DefaultRetention, "number of inactive versions to keep per project for rollbacks (0 to keep none)"),
+				flagSet.BoolVar(&options.Prune, "prune", false, "remove inactive versions beyond -keep-versions to reclaim space"),
+				flagSet.BoolVarP(&options.DisableChangeLog, "disable-changelog", "dc", false, "disable release changelog in output"),
+				flagSet.BoolVarP(&options.SkipChecksum, "skip-checksum", "sc", false, "install projectdiscovery releases without a checksums file unverified"),
+			)
+		},
+		apply: func(options *Options, args []string) error {
+			options.Update = projectArgs(args)
+			return requireProjects("update", options.Update, options.UpdateAll)
+		},
+	},
+	{
+		name:        "remove",
+		args:        "<project[@version]>...",
+		description: "remove single or multiple projects, name@version removes an inactive version",
+		flags: func(flagSet *goflags.FlagSet, options *Options) {
+			flagSet.CreateGroup("remove", "Remove",
+				flagSet.BoolVarP(&options.RemoveAll, "all", "a", false, "remove all the projects of the tool set"),
+				flagSet.BoolVarP(&options.UnSetPath, "remove-path", "rp", false, "remove path from PATH environment variables"),
+			)
+		},
+		apply: func(options *Options, args []string) error {
+			options.Remove = projectArgs(args)
+			return requireProjects("remove", options.Remove, options.RemoveAll)
+		},
+	},
+	{
+		name:        "list",
+		description: "list the projects of the tool set with their installed versions",
+		apply:       noArgs("list"),
+	},
+	{
+		name:        "info",
+		args:        "<project>...",
+		description: "show the release, installation, pin and installed versions of single or multiple projects",
+		apply: func(options *Options, args []string) error {
+			options.Info = projectArgs(args)
+			if len(options.Info) == 0 {
+				return errors.New("info: no project given")
+			}
+			return nil
+		},
+	},
+	{
+		name:        "outdated",
+		description: "list the installed projects with a newer, or other pinned, version and exit with 2 when there is any",
+		apply: func(options *Options, args []string) error {
+			options.Outdated = true
+			return noArgs("outdated")(options, args)
+		},
+	},
+	{
+		name:        "doctor",
+		description: "diagnose the binary path, configuration, tool sources and installed projects",
+		apply: func(options *Options, args []string) error {
+			options.Doctor = true
+			return noArgs("doctor")(options, args)
+		},
+	},
+	{
+		name:        "sync",
+		description: "install, update and downgrade projects to match the lockfile",
+		flags: func(flagSet *goflags.FlagSet, options *Options) {
+			flagSet.CreateGroup("sync", "Sync",
+				flagSet.BoolVar(&options.SyncExact, "exact", false, "remove installed projects missing from the lockfile"),
+				flagSet.BoolVarP(&options.UpdateLock, "update-lock", "ul", false, "resolve the manifest again and rewrite the lockfile before syncing"),
+				flagSet.StringVarP(&options.Manifest, "manifest", "mf", "pdtm.yaml", "project manifest listing projects and version constraints"),
+				flagSet.StringVarP(&options.Lockfile, "lockfile", "lf", "pdtm.lock", "lockfile with the exact resolved project versions"),
+				flagSet.BoolVarP(&options.SkipChecksum, "skip-checksum", "sc", false, "install projectdiscovery releases without a checksums file unverified"),
+			)
+		},
+		apply: func(options *Options, args []string) error {
+			options.Sync = true
+			return noArgs("sync")(options, args)
+		},
+	},
+	{
+		name:        "bundle create",
+		args:        "<file> [project[@version]...]",
+		description: "download release assets into an offline bundle file, all the projects of the tool set by default",
+		flags: func(flagSet *goflags.FlagSet, options *Options) {
+			flagSet.CreateGroup("bundle", "Bundle",
+				flagSet.StringSliceVarP(&options.BundlePlatforms, "platforms", "pl", nil, "os/arch platforms to bundle (comma separated), the current platform by default", goflags.NormalizedStringSliceOptions),
+			)
+		},
+		apply: func(options *Options, args []string) error {
+			if len(args) == 0 {
+				return errors.New("bundle create: no bundle file given")
+			}
+			options.BundleCreate, options.BundleTools = args[0], projectArgs(args[1:])
+			return nil
+		},
+	},
+	{
+		name:        "bundle install",
+		args:        "<file> [project...]",
+		description: "install projects from an offline bundle file without network access, all the bundled projects by default",
+		flags: func(flagSet *goflags.FlagSet, options *Options) {
+			flagSet.CreateGroup("bundle", "Bundle",
+				flagSet.IntVarP(&options.KeepVersions, "keep-versions", "kv", pkg.DefaultRetention, "number of inactive versions to keep per project for rollbacks (0 to keep none)"),
+			)
+		},
+		apply: func(options *Options, args []string) error {
+			if len(args) == 0 {
+				return errors.New("bundle install: no bundle file given")
+			}
+			options.BundleInstall, options.BundleTools = args[0], projectArgs(args[1:])
+			return nil
+		},
+	},
+	{
+		name:        "serve",
+		args:        "<address>",
+		description: "serve a local mirror of the pdtm api and release assets on the given address (e.g. :8080)",
+		flags: func(flagSet *goflags.FlagSet, options *Options) {
+			flagSet.CreateGroup("serve", "Serve",
+				flagSet.StringVarP(&options.ServeDir, "dir", "d", mirrorDir, "directory of the mirrored tool list and release assets"),
+				flagSet.StringVarP(&options.ServeURL, "url", "u", "", "public base url of the mirror, taken from requests by default"),
+				flagSet.DurationVarP(&options.ServeRefresh, "refresh", "r", time.Hour, "interval to refresh the mirrored tool list from the tool sources"),
+			)
+		},
+		apply: func(options *Options, args []string) error {
+			if len(args) != 1 {
+				return errors.New("serve: give exactly one address to listen on")
+			}
+			options.Serve = args[0]
+			return nil
+		},
+	},
+}
+
+// lookupCommand returns the command named by the first arguments
+func lookupCommand(args []string) (command, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && slices.Equal(words, args[:len(words)]) {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// commandNames lists the names of all commands
+func commandNames() string {
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// commandsHelp describes the commands in the usage of the legacy flags
+func commandsHelp() string {
+	var builder strings.Builder
+	builder.WriteString("COMMANDS:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&builder, "   %-14s %s\n", cmd.name, cmd.description)
+	}
+	builder.WriteString("\nRun 'pdtm <command> -h' for the flags of a command, the flags above remain available without a command.")
+	return builder.String()
+}
+
+func requireProjects(name string, args []string, all bool) error {
+	switch {
+	case all && len(args) > 0:
+		return fmt.Errorf("%s: projects cannot be given with -all", name)
+	case !all && len(args) == 0:
+		return fmt.Errorf("%s: no project given, use -all for all the projects", name)
+	}
+	return nil
+}
+
+func noArgs(name string) func(options *Options, args []string) error {
+	return func(_ *Options, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("%s: unexpected arguments %s", name, strings.Join(args, " "))
+		}
+		return nil
+	}
+}
+
+// projectArgs splits comma separated projects given as positional
+// arguments, like the flags taking projects
+func projectArgs(args []string) []string {
+	var projects []string
+	for _, arg := range args {
+		for _, project := range strings.Split(arg, ",") {
+			if project = strings.ToLower(strings.TrimSpace(project)); project != "" {
+				projects = append(projects, project)
+			}
+		}
+	}
+	return projects
+}
+
+// commandFlagSet creates the flag set of cmd, its own flags come first
+// followed by the config, network, GitHub, output and debug flags
+func (options *Options) commandFlagSet(cmd command) *goflags.FlagSet {
+	flagSet := goflags.NewFlagSet()
+	flagSet.SetDescription(cmd.description)
+	// goflags names the program after os.Args[0], the usage of the command
+	// is printed after the flags
+	flagSet.SetCustomHelpText(strings.TrimSpace(fmt.Sprintf("Usage of %s:\n  pdtm %s [flags] %s", cmd.name, cmd.name, cmd.args)))
+	if cmd.flags != nil {
+		cmd.flags(flagSet, options)
+	}
+	options.configFlags(flagSet)
+	options.networkFlags(flagSet)
+	options.githubFlags(flagSet)
+	options.outputFlags(flagSet)
+	flagSet.CreateGroup("debug", "Debug",
+		flagSet.BoolVarP(&options.Verbose, "verbose", "v", false, "show verbose output"),
+		flagSet.BoolVar(&options.Silent, "silent", false, "silence output"),
+		flagSet.BoolVarP(&options.NoColor, "no-color", "nc", false, "disable output content coloring (ANSI escape codes)"),
+		flagSet.BoolVarP(&options.DisableUpdateCheck, "disable-update-check", "duc", false, "disable automatic pdtm update check"),
+	)
+	return flagSet
+}
+
+// interspersed moves the positional arguments of a command after its
+// flags and a "--" separator, the flag package stops parsing at the first
+// positional argument otherwise
+func interspersed(commandLine *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := commandLine.Lookup(name)
+		if hasValue || f == nil {
+			continue
+		}
+		if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return append(append(flags, "--"), positional...)
+}
+
+// validate rejects flags selecting more than one operation, the runner
+// would otherwise run them one after another in a fixed order
+func (options *Options) validate() error {
+	operations := []struct {
+		flag     string
+		selected bool
+	}{
+		{"-install", len(options.Install) > 0},
+		{"-install-all", options.InstallAll},
+		{"-update", len(options.Update) > 0},
+		{"-update-all", options.UpdateAll},
+		{"-remove", len(options.Remove) > 0},
+		{"-remove-all", options.RemoveAll},
+		{"-use", len(options.Use) > 0},
+		{"-rollback", len(options.Rollback) > 0},
+		{"-list-versions", len(options.ListVersions) > 0},
+		{"-check", len(options.Check) > 0},
+		{"-check-all", options.CheckAll},
+		// -update-lock alone only rewrites the lockfile, with -sync it syncs afterwards
+		{"-sync/-update-lock", options.Sync || options.UpdateLock},
+		{"-bundle-create", options.BundleCreate != ""},
+		{"-bundle-install", options.BundleInstall != ""},
+		{"-serve", options.Serve != ""},
+	}
+	var selected []string
+	for _, operation := range operations {
+		if operation.selected {
+			selected = append(selected, operation.flag)
+		}
+	}
+	if len(selected) > 1 {
+		return fmt.Errorf("%s cannot be used with %s", selected[0], strings.Join(selected[1:], ", "))
+	}
+
+	var errs []error
+	for _, conflict := range []struct {
+		flag, other string
+		conflicts   bool
+	}{
+		{"-json", "-jsonl", options.JSON && options.JSONL},
+		{"-verbose", "-silent", options.Verbose && options.Silent},
+		{"-install-path", "-remove-path", options.SetPath && options.UnSetPath},
+	} {
+		if conflict.conflicts {
+			errs = append(errs, fmt.Errorf("%s cannot be used with %s", conflict.flag, conflict.other))
+		}
+	}
+	return errors.Join(errs...)
+}
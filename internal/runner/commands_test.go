@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/goflags"
+	"github.com/stretchr/testify/require"
+)
+
+// parseCommand parses args of the command named by args[0] like ParseOptions
+func parseCommand(t *testing.T, args ...string) (*Options, error) {
+	cmd, ok := lookupCommand(args)
+	require.True(t, ok, args[0])
+	options := &Options{}
+	flagSet := options.commandFlagSet(cmd)
+	flagSet.SetConfigFilePath(filepath.Join(t.TempDir(), "config.yaml"))
+	require.NoError(t, flagSet.Parse(interspersed(flagSet.CommandLine, cmd.trim(args))...))
+	if err := cmd.apply(options, flagSet.CommandLine.Args()); err != nil {
+		return options, err
+	}
+	return options, options.validate()
+}
+
+func TestCommands(t *testing.T) {
+	_, ok := lookupCommand([]string{"-install", "nuclei"})
+	require.False(t, ok)
+	_, ok = lookupCommand(nil)
+	require.False(t, ok)
+
+	options, err := parseCommand(t, "install", "Nuclei,httpx", "-bp", "/tmp/bin", "dnsx@1.2.0", "-json")
+	require.NoError(t, err)
+	require.Equal(t, goflags.StringSlice{"nuclei", "httpx", "dnsx@1.2.0"}, options.Install)
+	require.Equal(t, "/tmp/bin", options.Path)
+	require.True(t, options.JSON)
+
+	options, err = parseCommand(t, "update", "-all", "-kv", "1", "--", "-weird")
+	require.EqualError(t, err, "update: projects cannot be given with -all")
+	require.Equal(t, 1, options.KeepVersions)
+
+	options, err = parseCommand(t, "remove", "-a")
+	require.NoError(t, err)
+	require.True(t, options.RemoveAll)
+
+	_, err = parseCommand(t, "install")
+	require.EqualError(t, err, "install: no project given, use -all for all the projects")
+	_, err = parseCommand(t, "info")
+	require.EqualError(t, err, "info: no project given")
+	_, err = parseCommand(t, "doctor", "nuclei")
+	require.EqualError(t, err, "doctor: unexpected arguments nuclei")
+
+	options, err = parseCommand(t, "outdated", "-jsonl")
+	require.NoError(t, err)
+	require.True(t, options.Outdated)
+	require.True(t, options.JSONL)
+
+	_, err = parseCommand(t, "list", "-json", "-jsonl")
+	require.EqualError(t, err, "-json cannot be used with -jsonl")
+
+	options, err = parseCommand(t, "sync", "-ul", "-exact", "-lf", "ci.lock")
+	require.NoError(t, err)
+	require.True(t, options.Sync)
+	require.True(t, options.UpdateLock)
+	require.True(t, options.SyncExact)
+	require.Equal(t, "ci.lock", options.Lockfile)
+}
+
+func TestBundleAndServeCommands(t *testing.T) {
+	_, ok := lookupCommand([]string{"bundle"})
+	require.False(t, ok, "bundle needs a subcommand")
+
+	// file names keep their case, projects are normalized
+	options, err := parseCommand(t, "bundle", "create", "Tools.tar.gz", "Nuclei,httpx@1.6.0", "-pl", "linux/amd64,darwin/arm64")
+	require.NoError(t, err)
+	require.Equal(t, "Tools.tar.gz", options.BundleCreate)
+	require.Equal(t, goflags.StringSlice{"nuclei", "httpx@1.6.0"}, options.BundleTools)
+	require.Equal(t, goflags.StringSlice{"linux/amd64", "darwin/arm64"}, options.BundlePlatforms)
+
+	options, err = parseCommand(t, "bundle", "install", "/mnt/usb/Tools.tar.gz")
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/usb/Tools.tar.gz", options.BundleInstall)
+	require.Empty(t, options.BundleTools)
+	_, err = parseCommand(t, "bundle", "install")
+	require.EqualError(t, err, "bundle install: no bundle file given")
+
+	options, err = parseCommand(t, "serve", ":8080", "-d", "/srv/pdtm", "-r", "30m")
+	require.NoError(t, err)
+	require.Equal(t, ":8080", options.Serve)
+	require.Equal(t, "/srv/pdtm", options.ServeDir)
+	require.Equal(t, 30*time.Minute, options.ServeRefresh)
+	_, err = parseCommand(t, "serve")
+	require.EqualError(t, err, "serve: give exactly one address to listen on")
+}
+
+func TestInterspersed(t *testing.T) {
+	options := &Options{}
+	flagSet := options.commandFlagSet(commands[0])
+	require.Equal(t,
+		[]string{"-v", "-bp", "/tmp/bin", "-c=2", "-unknown", "--", "nuclei", "httpx", "-after"},
+		interspersed(flagSet.CommandLine, []string{"nuclei", "-v", "-bp", "/tmp/bin", "httpx", "-c=2", "-unknown", "--", "-after"}),
+	)
+	require.Equal(t, []string{"--"}, interspersed(flagSet.CommandLine, nil))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, (&Options{Install: goflags.StringSlice{"nuclei"}, Unpin: goflags.StringSlice{"httpx"}}).validate())
+	require.NoError(t, (&Options{UpdateAll: true, Prune: true}).validate())
+	require.NoError(t, (&Options{Sync: true, UpdateLock: true}).validate())
+
+	require.EqualError(t, (&Options{Install: goflags.StringSlice{"nuclei"}, Remove: goflags.StringSlice{"nuclei"}}).validate(),
+		"-install cannot be used with -remove")
+	require.EqualError(t, (&Options{UpdateAll: true, Update: goflags.StringSlice{"nuclei"}, CheckAll: true}).validate(),
+		"-update cannot be used with -update-all, -check-all")
+	require.EqualError(t, (&Options{Sync: true, Serve: ":8080"}).validate(),
+		"-sync/-update-lock cannot be used with -serve")
+	require.EqualError(t, (&Options{Verbose: true, Silent: true, SetPath: true, UnSetPath: true}).validate(),
+		"-verbose cannot be used with -silent\n-install-path cannot be used with -remove-path")
+}
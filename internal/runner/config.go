@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/config"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/utils"
+)
+
+// configFileArg returns the -config value given in args, the configuration
+// file has to be known before the flags are parsed
+func configFileArg(args []string) (string, bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// isSet reports whether any of the names of a flag was given on the command line
+func isSet(cliFlags map[string]bool, names ...string) bool {
+	for _, name := range names {
+		if cliFlags[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// applyConfig applies the configuration file, its selected profile and the
+// PDTM_* environment variables read with getenv to the options. Flags given
+// on the command line come first, then the environment, the profile and the
+// settings of the file.
+func (options *Options) applyConfig(cliFlags map[string]bool, getenv func(string) string) error {
+	file, err := config.Load(options.ConfigFile)
+	if err != nil {
+		return err
+	}
+	profile := options.Profile
+	if !isSet(cliFlags, "profile", "pf") {
+		profile = getenv(config.EnvProfile)
+	}
+	fileSettings, err := file.Resolve(profile)
+	if err != nil {
+		return err
+	}
+	envSettings, err := config.FromEnv(getenv)
+	if err != nil {
+		return err
+	}
+
+	var cliSettings config.Settings
+	if path := options.Path; isSet(cliFlags, "binary-path", "bp") {
+		cliSettings.BinaryPath = &path
+	}
+	if concurrency := options.Concurrency; isSet(cliFlags, "concurrency", "c") {
+		cliSettings.Concurrency = &concurrency
+	}
+	if proxy := options.Proxy; isSet(cliFlags, "proxy") {
+		cliSettings.Proxy = &proxy
+	}
+	settings := config.Merge(cliSettings, envSettings, fileSettings)
+
+	// goflags merged the flag keys of the file already, the schema keys are
+	// set again so the environment and profiles take precedence over it
+	options.Path, options.Concurrency, options.Proxy = defaultPath, defaultConcurrency, ""
+	if settings.BinaryPath != nil {
+		options.Path = expandHome(*settings.BinaryPath)
+	}
+	if settings.Concurrency != nil {
+		options.Concurrency = *settings.Concurrency
+	}
+	if settings.Proxy != nil {
+		options.Proxy = *settings.Proxy
+	}
+	options.Tools, options.DisabledTools = settings.Tools, settings.DisabledTools
+	options.ConfigGithubTokens = settings.GithubTokens
+	return nil
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path == "~" {
+		return homeDir
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(homeDir, rest)
+	}
+	return path
+}
+
+// selectedTools returns the tools of the configured tool set, or the whole
+// tool list without one, leaving out the disabled tools
+func (r *Runner) selectedTools(toolList []types.Tool) []types.Tool {
+	var tools []types.Tool
+	if len(r.options.Tools) == 0 {
+		tools = toolList
+	} else {
+		for _, toolName := range r.options.Tools {
+			i, ok := utils.Contains(toolList, toolName)
+			if !ok {
+				gologger.Warning().Msgf("%s: configured tool %s", toolName, errNotInList)
+				continue
+			}
+			tools = append(tools, toolList[i])
+		}
+	}
+	if len(r.options.DisabledTools) == 0 {
+		return tools
+	}
+	var enabled []types.Tool
+	for _, tool := range tools {
+		if !r.isDisabled(tool.Name) {
+			enabled = append(enabled, tool)
+		}
+	}
+	return enabled
+}
+
+// isDisabled reports whether toolName is disabled in the configuration
+func (r *Runner) isDisabled(toolName string) bool {
+	for _, disabled := range r.options.DisabledTools {
+		if strings.EqualFold(disabled, toolName) {
+			return true
+		}
+	}
+	return false
+}
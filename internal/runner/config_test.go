@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFileArg(t *testing.T) {
+	for _, args := range [][]string{
+		{"-config", "a.yaml"},
+		{"-i", "nuclei", "--config", "a.yaml"},
+		{"-config=a.yaml"},
+		{"--config=a.yaml", "-silent"},
+	} {
+		file, ok := configFileArg(args)
+		require.True(t, ok, args)
+		require.Equal(t, "a.yaml", file, args)
+	}
+	for _, args := range [][]string{
+		nil,
+		{"-i", "config"},
+		{"-config"},
+		{"--", "-config", "a.yaml"},
+	} {
+		_, ok := configFileArg(args)
+		require.False(t, ok, args)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+binary-path: ~/tools
+concurrency: 2
+proxy: http://file:8080
+github-tokens:
+  github.com: file-token
+disabled-tools: [uncover]
+profiles:
+  recon:
+    tools: [subfinder, httpx]
+    concurrency: 3
+`), 0644))
+
+	apply := func(options *Options, cliFlags map[string]bool, env map[string]string) *Options {
+		options.ConfigFile = configFile
+		require.NoError(t, options.applyConfig(cliFlags, func(key string) string { return env[key] }))
+		return options
+	}
+
+	options := apply(&Options{}, nil, nil)
+	require.Equal(t, filepath.Join(homeDir, "tools"), options.Path)
+	require.Equal(t, 2, options.Concurrency)
+	require.Equal(t, "http://file:8080", options.Proxy)
+	require.Nil(t, options.Tools)
+	require.Equal(t, []string{"uncover"}, options.DisabledTools)
+	require.Equal(t, map[string]string{"github.com": "file-token"}, options.ConfigGithubTokens)
+
+	options = apply(&Options{}, nil, map[string]string{"PDTM_PROFILE": "recon"})
+	require.Equal(t, 3, options.Concurrency, "profile over file")
+	require.Equal(t, []string{"subfinder", "httpx"}, options.Tools)
+
+	options = apply(&Options{}, nil, map[string]string{"PDTM_PROFILE": "recon", "PDTM_CONCURRENCY": "4", "PDTM_BINARY_PATH": "/env"})
+	require.Equal(t, 4, options.Concurrency, "env over profile")
+	require.Equal(t, "/env", options.Path)
+
+	options = apply(&Options{Profile: "recon", Concurrency: 5, Proxy: "http://cli:8080"}, map[string]bool{"pf": true, "c": true, "proxy": true},
+		map[string]string{"PDTM_PROFILE": "web", "PDTM_CONCURRENCY": "4"})
+	require.Equal(t, 5, options.Concurrency, "cli over env")
+	require.Equal(t, "http://cli:8080", options.Proxy)
+	require.Equal(t, []string{"subfinder", "httpx"}, options.Tools, "cli profile over env")
+
+	// values goflags merged from the file are replaced by the resolved ones
+	options = apply(&Options{Path: "/merged", Concurrency: 2}, nil, map[string]string{"PDTM_PROFILE": "recon"})
+	require.Equal(t, filepath.Join(homeDir, "tools"), options.Path)
+	require.Equal(t, 3, options.Concurrency)
+
+	options = &Options{ConfigFile: configFile, Profile: "web"}
+	require.EqualError(t, options.applyConfig(map[string]bool{"profile": true}, os.Getenv), `unknown profile "web" (profiles: recon)`)
+	options = &Options{ConfigFile: filepath.Join(t.TempDir(), "missing.yaml")}
+	require.NoError(t, options.applyConfig(nil, func(string) string { return "" }))
+	require.Equal(t, defaultPath, options.Path)
+	require.Equal(t, defaultConcurrency, options.Concurrency)
+}
+
+func TestSelectedTools(t *testing.T) {
+	toolList := []types.Tool{{Name: "nuclei"}, {Name: "httpx"}, {Name: "uncover"}, {Name: "subfinder"}}
+
+	r := &Runner{options: &Options{DisabledTools: []string{"Uncover"}}}
+	require.Equal(t, []types.Tool{{Name: "nuclei"}, {Name: "httpx"}, {Name: "subfinder"}}, r.selectedTools(toolList))
+	require.True(t, r.isDisabled("uncover"))
+
+	r.options.Tools = []string{"subfinder", "uncover", "gau", "httpx"}
+	require.Equal(t, []types.Tool{{Name: "subfinder"}, {Name: "httpx"}}, r.selectedTools(toolList))
+
+	result := r.installTool(toolList, "uncover")
+	require.Equal(t, statusSkipped, result.Status)
+	require.Equal(t, "disabled in config", result.Reason)
+}
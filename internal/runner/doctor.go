@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/receipt"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+type diagnosisStatus string
+
+const (
+	diagnosisOK      diagnosisStatus = "ok"
+	diagnosisWarning diagnosisStatus = "warning"
+	diagnosisError   diagnosisStatus = "error"
+)
+
+// diagnosis is the outcome of a single doctor check
+type diagnosis struct {
+	Check  string          `json:"check"`
+	Status diagnosisStatus `json:"status"`
+	Detail string          `json:"detail"`
+}
+
+// doctor diagnoses the setup of pdtm and returns an ExitError with
+// ExitCodeError when any check failed
+func (r *Runner) doctor() error {
+	toolList, err := r.loadToolList(false)
+	diagnoses := r.diagnose(toolList, err)
+
+	var hasError bool
+	for _, d := range diagnoses {
+		hasError = hasError || d.Status == diagnosisError
+	}
+	if r.jsonOutput() {
+		if err := writeRecords(diagnoses, r.options.JSONL); err != nil {
+			return &ExitError{Code: ExitCodeError, Err: err}
+		}
+	} else {
+		builder := &strings.Builder{}
+		table := tabwriter.NewWriter(builder, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(table, "CHECK\tSTATUS\tDETAILS")
+		for _, d := range diagnoses {
+			_, _ = fmt.Fprintf(table, "%s\t%s\t%s\n", d.Check, d.Status, d.Detail)
+		}
+		_ = table.Flush()
+		gologger.Print().Msgf("\n%s", builder.String())
+	}
+	if hasError {
+		return &ExitError{Code: ExitCodeError}
+	}
+	return nil
+}
+
+// diagnose checks the binary path, the configuration, the tool sources,
+// GitHub authentication, go and the installed tools. toolListErr is the
+// error loading toolList.
+func (r *Runner) diagnose(toolList []types.Tool, toolListErr error) []diagnosis {
+	diagnoses := []diagnosis{r.diagnoseBinaryPath()}
+
+	if path.IsSet(r.options.Path) {
+		diagnoses = append(diagnoses, diagnosis{"$PATH", diagnosisOK, r.options.Path + " is in $PATH"})
+	} else {
+		diagnoses = append(diagnoses, diagnosis{"$PATH", diagnosisWarning, r.options.Path + " is not in $PATH, run pdtm -install-path"})
+	}
+
+	if fileutil.FileExists(r.options.ConfigFile) {
+		diagnoses = append(diagnoses, diagnosis{"config", diagnosisOK, "loaded " + r.options.ConfigFile})
+	} else {
+		diagnoses = append(diagnoses, diagnosis{"config", diagnosisOK, r.options.ConfigFile + " not found, using defaults"})
+	}
+
+	if toolListErr != nil {
+		diagnoses = append(diagnoses, diagnosis{"tool sources", diagnosisError, toolListErr.Error()})
+	} else {
+		diagnoses = append(diagnoses, diagnosis{"tool sources", diagnosisOK, fmt.Sprintf("%d projects listed", len(toolList))})
+	}
+
+	if pkg.GithubAuthenticated() {
+		diagnoses = append(diagnoses, diagnosis{"github", diagnosisOK, "api requests are authenticated"})
+	} else {
+		diagnoses = append(diagnoses, diagnosis{"github", diagnosisWarning, "api requests are unauthenticated and limited to 60 per hour, set GITHUB_TOKEN"})
+	}
+
+	if isGoInstalled() {
+		diagnoses = append(diagnoses, diagnosis{"go", diagnosisOK, "go install is available as fallback"})
+	} else {
+		diagnoses = append(diagnoses, diagnosis{"go", diagnosisWarning, "go is not installed, projects without a release asset for this platform cannot be installed"})
+	}
+
+	return append(diagnoses, r.diagnoseTools(toolList)...)
+}
+
+// diagnoseBinaryPath checks that tools can be installed in the binary path
+func (r *Runner) diagnoseBinaryPath() diagnosis {
+	const check = "binary path"
+	if !path.IsSubPath(homeDir, r.options.Path) {
+		return diagnosis{check, diagnosisError, r.options.Path + " is outside the home folder, installs are skipped"}
+	}
+	info, err := os.Stat(r.options.Path)
+	if os.IsNotExist(err) {
+		return diagnosis{check, diagnosisWarning, r.options.Path + " does not exist yet, it is created by the first install"}
+	}
+	if err != nil {
+		return diagnosis{check, diagnosisError, err.Error()}
+	}
+	if !info.IsDir() {
+		return diagnosis{check, diagnosisError, r.options.Path + " is not a directory"}
+	}
+	probe, err := os.CreateTemp(r.options.Path, ".pdtm-doctor-*")
+	if err != nil {
+		return diagnosis{check, diagnosisError, fmt.Sprintf("%s is not writable: %s", r.options.Path, errors.Unwrap(err))}
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+	return diagnosis{check, diagnosisOK, r.options.Path + " is writable"}
+}
+
+// diagnoseTools checks the installed tools against their receipts and the
+// versions installed side by side against their shims
+func (r *Runner) diagnoseTools(toolList []types.Tool) []diagnosis {
+	var diagnoses []diagnosis
+	var installed int
+	for _, tool := range toolList {
+		if _, exists := path.GetEntryPath(r.options.Path, tool.Name); !exists {
+			continue
+		}
+		installed++
+		if _, drift, ok := pkg.InstalledReceipt(r.options.Path, tool.Name); ok && drift != receipt.NoDrift {
+			diagnoses = append(diagnoses, diagnosis{tool.Name, diagnosisWarning, fmt.Sprintf("binary %s since install", drift)})
+		}
+	}
+
+	toolNames, err := pkg.VersionedTools(r.options.Path)
+	if err != nil {
+		diagnoses = append(diagnoses, diagnosis{"versions", diagnosisError, err.Error()})
+	}
+	for _, toolName := range toolNames {
+		active, ok := path.ActiveVersion(r.options.Path, toolName)
+		_, hasBinary := path.ActiveExecutablePath(r.options.Path, toolName)
+		_, hasShim := path.GetEntryPath(r.options.Path, toolName)
+		switch {
+		case !ok:
+			diagnoses = append(diagnoses, diagnosis{toolName, diagnosisError, "no active version, run pdtm -use " + toolName + "@<version>"})
+		case !hasBinary:
+			diagnoses = append(diagnoses, diagnosis{toolName, diagnosisError, fmt.Sprintf("active version %s is not installed, run pdtm -use %s@%s", active, toolName, active)})
+		case !hasShim:
+			diagnoses = append(diagnoses, diagnosis{toolName, diagnosisError, fmt.Sprintf("shim is missing, run pdtm -use %s@%s", toolName, active)})
+		}
+	}
+
+	if len(diagnoses) == 0 {
+		diagnoses = append(diagnoses, diagnosis{"projects", diagnosisOK, fmt.Sprintf("%d installed projects are healthy", installed)})
+	}
+	return diagnoses
+}
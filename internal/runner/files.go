@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"os"
+	"strings"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// applyFilesManifest sets the auxiliary files declared for each tool in the
+// files manifest, which maps tool names to the files to install from their
+// release archives:
+//
+//	nuclei:
+//	  - pattern: completions/nuclei.bash
+//	    kind: bash-completion
+//
+// A missing manifest leaves the tools as listed by their source.
+func applyFilesManifest(file string, toolList []types.Tool) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var declared map[string][]types.ToolFile
+	if err := fileutil.UnmarshalFromReader(fileutil.YAML, f, &declared); err != nil {
+		return err
+	}
+	for name, files := range declared {
+		for i := range toolList {
+			if strings.EqualFold(toolList[i].Name, name) {
+				toolList[i].Files = files
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// infoRecord is the machine readable description of a tool for pdtm info
+type infoRecord struct {
+	toolRecord
+	Owner    string            `json:"owner"`
+	Disabled bool              `json:"disabled,omitempty"`
+	Versions []pkg.ToolVersion `json:"versions,omitempty"`
+}
+
+// info prints the release, installation, pin and installed versions of the
+// given tools
+func (r *Runner) info(toolList []types.Tool, toolNames []string) error {
+	var hasError bool
+	var records []infoRecord
+	for _, toolName := range toolNames {
+		tool, unlisted, err := lookupTool(toolList, toolName)
+		if err == nil && unlisted {
+			err = errNotInList
+		}
+		if err != nil {
+			gologger.Error().Msgf("%s: %s", toolName, err)
+			hasError = true
+			continue
+		}
+		versions, err := pkg.Versions(r.options.Path, tool.Name)
+		if err != nil {
+			gologger.Warning().Msgf("%s: could not read installed versions: %s", tool.Name, err)
+		}
+		record := infoRecord{
+			toolRecord: r.newToolRecord(tool),
+			Owner:      tool.GetOwner(),
+			Disabled:   r.isDisabled(tool.Name),
+			Versions:   versions,
+		}
+		records = append(records, record)
+		if !r.jsonOutput() {
+			printInfo(record)
+		}
+	}
+	if r.jsonOutput() {
+		if err := writeRecords(records, r.options.JSONL); err != nil {
+			return err
+		}
+	}
+	if hasError {
+		return &ExitError{Code: ExitCodeError}
+	}
+	return nil
+}
+
+func printInfo(record infoRecord) {
+	builder := &strings.Builder{}
+	table := tabwriter.NewWriter(builder, 0, 0, 2, ' ', 0)
+	row := func(name, value string) {
+		_, _ = fmt.Fprintf(table, "  %s:\t%s\n", name, valueOrDash(value))
+	}
+	row("repository", record.Owner+"/"+record.Repo)
+	row("latest", record.LatestVersion)
+	installed := string(record.Status)
+	if record.InstalledVersion != "" {
+		installed = fmt.Sprintf("%s (%s)", record.InstalledVersion, record.Status)
+	}
+	row("installed", installed)
+	row("pinned", record.PinnedVersion)
+	row("path", record.Path)
+	row("install type", string(record.InstallType))
+	if record.InstalledAt != nil {
+		row("installed at", record.InstalledAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if record.Drift != "" {
+		row("drift", string(record.Drift)+" since install")
+	}
+	versions := make([]string, 0, len(record.Versions))
+	for _, tv := range record.Versions {
+		versions = append(versions, tv.Version+versionLabels(versionRecord{ToolVersion: tv}))
+	}
+	row("versions", strings.Join(versions, ", "))
+	if record.Disabled {
+		row("disabled", "in config")
+	}
+	_ = table.Flush()
+	fmt.Printf("%s\n%s", record.Name, builder.String())
+}
+
+// outdated prints the installed tools of the tool set that are behind their
+// latest, or pinned, version and returns an ExitError with ExitCodeOutdated
+// when there is any
+func (r *Runner) outdated(toolList []types.Tool) error {
+	var records []toolRecord
+	builder := &strings.Builder{}
+	table := tabwriter.NewWriter(builder, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "TOOL\tINSTALLED\tWANTED\tLATEST")
+	for _, tool := range r.selectedTools(toolList) {
+		latest := tool.Version
+		// pinned tools are expected at their pinned version
+		if version, ok := r.pins.Get(tool.Name); ok {
+			tool.Version = version
+		}
+		record := r.newToolRecord(tool)
+		if record.Status != types.StatusOutdated {
+			continue
+		}
+		record.LatestVersion = latest
+		records = append(records, record)
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", tool.Name, record.InstalledVersion, tool.Version, latest)
+	}
+	_ = table.Flush()
+
+	if r.jsonOutput() {
+		if err := writeRecords(records, r.options.JSONL); err != nil {
+			return &ExitError{Code: ExitCodeError, Err: err}
+		}
+	} else if len(records) == 0 {
+		gologger.Info().Msg("all installed projects are up to date")
+	} else {
+		fmt.Print(builder.String())
+	}
+	if len(records) > 0 {
+		return &ExitError{Code: ExitCodeOutdated}
+	}
+	return nil
+}
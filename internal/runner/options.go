@@ -1,14 +1,19 @@
 package runner
 
 import (
+	"flag"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/logrusorgru/aurora/v4"
 	"github.com/projectdiscovery/goflags"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/gologger/formatter"
 	"github.com/projectdiscovery/gologger/levels"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/httpclient"
 	fileutil "github.com/projectdiscovery/utils/file"
 	updateutils "github.com/projectdiscovery/utils/update"
 )
@@ -25,6 +30,12 @@ var (
 
 	defaultConfigLocation = filepath.Join(homeDir, ".config/pdtm/config.yaml")
 	cacheFile             = filepath.Join(homeDir, ".config/pdtm/cache.json")
+	pinFile               = filepath.Join(homeDir, ".config/pdtm/pins.json")
+	registryFile          = filepath.Join(homeDir, ".config/pdtm/tools.yaml")
+	releasesCacheFile     = filepath.Join(homeDir, ".config/pdtm/releases.json")
+	receiptsFile          = filepath.Join(homeDir, ".config/pdtm/receipts.json")
+	filesManifestFile     = filepath.Join(homeDir, ".config/pdtm/files.yaml")
+	mirrorDir             = filepath.Join(homeDir, ".config/pdtm/mirror")
 	defaultPath           = filepath.Join(homeDir, ".pdtm/go/bin")
 )
 
@@ -32,73 +43,129 @@ var au *aurora.Aurora
 
 // Options contains the configuration options for tuning the enumeration process.
 type Options struct {
-	ConfigFile string
-	Path       string
-	NoColor    bool
-	SetPath    bool
-	SetGoPath  bool
-	UnSetPath  bool
+	ConfigFile  string
+	Profile     string
+	Path        string
+	Concurrency int
+	Sources     goflags.StringSlice
+	NoColor     bool
+	SetPath     bool
+	SetGoPath   bool
+	UnSetPath   bool
 
-	Install goflags.StringSlice
-	Update  goflags.StringSlice
-	Remove  goflags.StringSlice
+	// Tools and DisabledTools are the tool set and disabled tools of the
+	// configuration file or environment
+	Tools         []string
+	DisabledTools []string
+
+	Install      goflags.StringSlice
+	Update       goflags.StringSlice
+	Remove       goflags.StringSlice
+	Unpin        goflags.StringSlice
+	Check        goflags.StringSlice
+	Rollback     goflags.StringSlice
+	Use          goflags.StringSlice
+	ListVersions goflags.StringSlice
 
 	InstallAll bool
 	UpdateAll  bool
 	RemoveAll  bool
 
+	CheckAll bool
+
+	// Info, Outdated and Doctor are only selected by commands
+	Info     []string
+	Outdated bool
+	Doctor   bool
+
+	KeepVersions int
+	Prune        bool
+	SkipChecksum bool
+
+	BundleCreate    string
+	BundleInstall   string
+	BundleTools     goflags.StringSlice
+	BundlePlatforms goflags.StringSlice
+
+	Serve        string
+	ServeDir     string
+	ServeURL     string
+	ServeRefresh time.Duration
+
+	Manifest   string
+	Lockfile   string
+	Sync       bool
+	SyncExact  bool
+	UpdateLock bool
+
+	Proxy          string
+	CAFile         string
+	Insecure       bool
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	UserAgent      string
+
+	GithubAPIURL      string
+	GithubUploadURL   string
+	GithubDownloadURL string
+	GithubTokens      goflags.StringSlice
+	// ConfigGithubTokens are the tokens by host of the configuration file
+	ConfigGithubTokens map[string]string
+
+	JSON               bool
+	JSONL              bool
 	Verbose            bool
 	Silent             bool
 	Version            bool
 	ShowPath           bool
+	SelfUpdate         bool
 	DisableUpdateCheck bool
 	DisableChangeLog   bool
 }
 
-// ParseOptions parses the command line flags provided by a user
+// ParseOptions parses the command line flags provided by a user, either the
+// flags of the command given as first argument or the legacy flags
 func ParseOptions() *Options {
-	options := &Options{}
-	flagSet := goflags.NewFlagSet()
-
-	flagSet.SetDescription(`pdtm is a simple and easy-to-use golang based tool for managing open source projects from ProjectDiscovery`)
-
-	flagSet.CreateGroup("config", "Config",
-		flagSet.StringVar(&options.ConfigFile, "config", defaultConfigLocation, "cli flag configuration file"),
-		flagSet.StringVarP(&options.Path, "binary-path", "bp", defaultPath, "custom location to download project binary"),
-	)
-
-	flagSet.CreateGroup("install", "Install",
-		flagSet.StringSliceVarP(&options.Install, "install", "i", nil, "install single or multiple project by name (comma separated)", goflags.NormalizedStringSliceOptions),
-		flagSet.BoolVarP(&options.InstallAll, "install-all", "ia", false, "install all the projects"),
-		flagSet.BoolVarP(&options.SetPath, "install-path", "ip", false, "append path to PATH environment variables"),
-		flagSet.BoolVarP(&options.SetGoPath, "install-go-path", "igp", false, "append GOBIN/GOPATH to PATH environment variables"),
-	)
-
-	flagSet.CreateGroup("update", "Update",
-		flagSet.StringSliceVarP(&options.Update, "update", "u", nil, "update single or multiple project by name (comma separated)", goflags.NormalizedStringSliceOptions),
-		flagSet.BoolVarP(&options.UpdateAll, "update-all", "ua", false, "update all the projects"),
-		flagSet.CallbackVarP(GetUpdateCallback(), "self-update", "up", "update pdtm to latest version"),
-		flagSet.BoolVarP(&options.DisableUpdateCheck, "disable-update-check", "duc", false, "disable automatic pdtm update check"),
-	)
-
-	flagSet.CreateGroup("remove", "Remove",
-		flagSet.StringSliceVarP(&options.Remove, "remove", "r", nil, "remove single or multiple project by name (comma separated)", goflags.NormalizedStringSliceOptions),
-		flagSet.BoolVarP(&options.RemoveAll, "remove-all", "ra", false, "remove all the projects"),
-		flagSet.BoolVarP(&options.UnSetPath, "remove-path", "rp", false, "remove path from PATH environment variables"),
-	)
+	// options without a flag in the flag set of a command keep their defaults
+	options := &Options{KeepVersions: pkg.DefaultRetention}
+	args := os.Args[1:]
+	cmd, isCommand := lookupCommand(args)
+	var flagSet *goflags.FlagSet
+	if isCommand {
+		flagSet = options.commandFlagSet(cmd)
+		args = interspersed(flagSet.CommandLine, cmd.trim(args))
+	} else {
+		flagSet = options.legacyFlagSet()
+	}
 
-	flagSet.CreateGroup("debug", "Debug",
-		flagSet.BoolVarP(&options.ShowPath, "show-path", "sp", false, "show the current binary path then exit"),
-		flagSet.BoolVar(&options.Version, "version", false, "show version of the project"),
-		flagSet.BoolVarP(&options.Verbose, "verbose", "v", false, "show verbose output"),
-		flagSet.BoolVar(&options.Silent, "silent", false, "silence output"),
-		flagSet.BoolVarP(&options.NoColor, "no-color", "nc", false, "disable output content coloring (ANSI escape codes)"),
-		flagSet.BoolVarP(&options.DisableChangeLog, "dc", "disable-changelog", false, "disable release changelog in output"),
-	)
+	// goflags merges the flag keys of the configuration file while parsing
+	configFile := defaultConfigLocation
+	if file, ok := configFileArg(os.Args[1:]); ok {
+		if !fileutil.FileExists(file) {
+			gologger.Fatal().Msgf("Config file %s does not exist\n", file)
+		}
+		configFile = file
+	}
+	flagSet.SetConfigFilePath(configFile)
 
-	if err := flagSet.Parse(); err != nil {
+	if err := flagSet.Parse(args...); err != nil {
+		gologger.Fatal().Msgf("%s\n", err)
+	}
+	positional := flagSet.CommandLine.Args()
+	if isCommand {
+		if err := cmd.apply(options, positional); err != nil {
+			gologger.Fatal().Msgf("%s\n", err)
+		}
+	} else if len(positional) > 0 {
+		gologger.Fatal().Msgf("unknown command %q, commands are %s\n", positional[0], commandNames())
+	}
+	if err := options.validate(); err != nil {
 		gologger.Fatal().Msgf("%s\n", err)
 	}
+	cliFlags := make(map[string]bool)
+	flagSet.CommandLine.Visit(func(f *flag.Flag) {
+		cliFlags[f.Name] = true
+	})
 
 	// configure aurora for logging
 	au = aurora.New(aurora.WithColors(true))
@@ -114,13 +181,33 @@ func ParseOptions() *Options {
 		os.Exit(0)
 	}
 
+	if err := options.applyConfig(cliFlags, os.Getenv); err != nil {
+		gologger.Fatal().Msgf("Could not load config file: %s\n", err)
+	}
+
 	if options.ShowPath {
 		// prints default path if not modified
 		gologger.Silent().Msg(options.Path)
 		os.Exit(0)
 	}
 
-	if !options.DisableUpdateCheck {
+	if err := options.configureHTTPClient(); err != nil {
+		gologger.Fatal().Msgf("Could not configure http client: %s\n", err)
+	}
+	if err := options.configureGithub(); err != nil {
+		gologger.Fatal().Msgf("Could not configure GitHub: %s\n", err)
+	}
+
+	// the update runs once the network and GitHub options are applied
+	if options.SelfUpdate {
+		if err := selfUpdate(); err != nil {
+			gologger.Fatal().Msgf("Could not update pdtm: %s\n", err)
+		}
+		os.Exit(0)
+	}
+
+	// bundles are installed on hosts without network access
+	if !options.DisableUpdateCheck && options.BundleInstall == "" {
 		latestVersion, err := updateutils.GetToolVersionCallback("pdtm", version)()
 		if err != nil {
 			if options.Verbose {
@@ -131,13 +218,132 @@ func ParseOptions() *Options {
 		}
 	}
 
-	if options.ConfigFile != defaultConfigLocation {
-		_ = options.loadConfigFrom(options.ConfigFile)
-	}
-
 	return options
 }
 
+// legacyFlagSet creates the flag set used without a command, every
+// operation is selected by a flag
+func (options *Options) legacyFlagSet() *goflags.FlagSet {
+	flagSet := goflags.NewFlagSet()
+
+	flagSet.SetDescription(`pdtm is a simple and easy-to-use golang based tool for managing open source projects from ProjectDiscovery`)
+	flagSet.SetCustomHelpText(commandsHelp())
+
+	options.configFlags(flagSet)
+
+	flagSet.CreateGroup("install", "Install",
+		flagSet.StringSliceVarP(&options.Install, "install", "i", nil, "install single or multiple project by name (comma separated), name@version installs and pins a specific version", goflags.NormalizedStringSliceOptions),
+		flagSet.BoolVarP(&options.InstallAll, "install-all", "ia", false, "install all the projects"),
+		flagSet.BoolVarP(&options.SetPath, "install-path", "ip", false, "append path to PATH environment variables"),
+		flagSet.BoolVarP(&options.SetGoPath, "install-go-path", "igp", false, "append GOBIN/GOPATH to PATH environment variables"),
+		flagSet.BoolVarP(&options.SkipChecksum, "skip-checksum", "sc", false, "install projectdiscovery releases without a checksums file unverified"),
+	)
+
+	flagSet.CreateGroup("update", "Update",
+		flagSet.StringSliceVarP(&options.Update, "update", "u", nil, "update single or multiple project by name (comma separated), name@version updates and pins a specific version", goflags.NormalizedStringSliceOptions),
+		flagSet.BoolVarP(&options.UpdateAll, "update-all", "ua", false, "update all the projects (pinned projects stay on their pinned version)"),
+		flagSet.StringSliceVar(&options.Unpin, "unpin", nil, "unpin single or multiple project by name (comma separated)", goflags.NormalizedStringSliceOptions),
+		flagSet.BoolVarP(&options.SelfUpdate, "self-update", "up", false, "update pdtm to latest version"),
+		flagSet.BoolVarP(&options.DisableUpdateCheck, "disable-update-check", "duc", false, "disable automatic pdtm update check"),
+	)
+
+	flagSet.CreateGroup("remove", "Remove",
+		flagSet.StringSliceVarP(&options.Remove, "remove", "r", nil, "remove single or multiple project by name (comma separated), name@version removes an inactive version", goflags.NormalizedStringSliceOptions),
+		flagSet.BoolVarP(&options.RemoveAll, "remove-all", "ra", false, "remove all the projects"),
+		flagSet.BoolVarP(&options.UnSetPath, "remove-path", "rp", false, "remove path from PATH environment variables"),
+	)
+
+	flagSet.CreateGroup("versions", "Versions",
+		flagSet.StringSliceVarP(&options.Use, "use", "us", nil, "make name@version the active version of single or multiple project (comma separated), installing it side by side when missing, and pin it", goflags.NormalizedStringSliceOptions),
+		flagSet.StringSliceVarP(&options.ListVersions, "list-versions", "lv", nil, "list the versions of single or multiple project (comma separated) installed side by side", goflags.NormalizedStringSliceOptions),
+		flagSet.StringSliceVarP(&options.Rollback, "rollback", "rb", nil, "switch single or multiple project (comma separated) back to the previous installed version, name@version to a specific one, and pin it", goflags.NormalizedStringSliceOptions),
+		flagSet.IntVarP(&options.KeepVersions, "keep-versions", "kv", pkg.DefaultRetention, "number of inactive versions to keep per project for rollbacks, versions selected with -use are always kept (0 to keep none)"),
+		flagSet.BoolVar(&options.Prune, "prune", false, "remove inactive versions beyond -keep-versions to reclaim space"),
+	)
+
+	flagSet.CreateGroup("check", "Check",
+		flagSet.StringSliceVarP(&options.Check, "check", "ck", nil, "check single or multiple project by name (comma separated) and exit non-zero when outdated or missing", goflags.NormalizedStringSliceOptions),
+		flagSet.BoolVarP(&options.CheckAll, "check-all", "cka", false, "check all the projects and exit non-zero when any is outdated or missing"),
+	)
+
+	flagSet.CreateGroup("sync", "Sync",
+		flagSet.BoolVar(&options.Sync, "sync", false, "install, update and downgrade projects to match the lockfile"),
+		flagSet.BoolVar(&options.SyncExact, "exact", false, "remove installed projects missing from the lockfile when syncing"),
+		flagSet.BoolVarP(&options.UpdateLock, "update-lock", "ul", false, "resolve the manifest again and rewrite the lockfile"),
+		flagSet.StringVarP(&options.Manifest, "manifest", "mf", "pdtm.yaml", "project manifest listing projects and version constraints"),
+		flagSet.StringVarP(&options.Lockfile, "lockfile", "lf", "pdtm.lock", "lockfile with the exact resolved project versions"),
+	)
+
+	flagSet.CreateGroup("bundle", "Bundle",
+		flagSet.StringVarP(&options.BundleCreate, "bundle-create", "bc", "", "download release assets into an offline bundle file"),
+		flagSet.StringVarP(&options.BundleInstall, "bundle-install", "bi", "", "install projects from an offline bundle file without network access"),
+		flagSet.StringSliceVarP(&options.BundleTools, "bundle-tools", "bt", nil, "projects to bundle or install from a bundle (comma separated, name@version), all by default", goflags.NormalizedStringSliceOptions),
+		flagSet.StringSliceVarP(&options.BundlePlatforms, "bundle-platforms", "bpl", nil, "os/arch platforms to bundle (comma separated), the current platform by default", goflags.NormalizedStringSliceOptions),
+	)
+
+	flagSet.CreateGroup("serve", "Serve",
+		flagSet.StringVar(&options.Serve, "serve", "", "serve a local mirror of the pdtm api and release assets on the given address (e.g. :8080)"),
+		flagSet.StringVarP(&options.ServeDir, "serve-dir", "sd", mirrorDir, "directory of the mirrored tool list and release assets"),
+		flagSet.StringVarP(&options.ServeURL, "serve-url", "su", "", "public base url of the mirror, taken from requests by default"),
+		flagSet.DurationVarP(&options.ServeRefresh, "serve-refresh", "sr", time.Hour, "interval to refresh the mirrored tool list from the tool sources"),
+	)
+
+	options.networkFlags(flagSet)
+	options.githubFlags(flagSet)
+	options.outputFlags(flagSet)
+
+	flagSet.CreateGroup("debug", "Debug",
+		flagSet.BoolVarP(&options.ShowPath, "show-path", "sp", false, "show the current binary path then exit"),
+		flagSet.BoolVar(&options.Version, "version", false, "show version of the project"),
+		flagSet.BoolVarP(&options.Verbose, "verbose", "v", false, "show verbose output"),
+		flagSet.BoolVar(&options.Silent, "silent", false, "silence output"),
+		flagSet.BoolVarP(&options.NoColor, "no-color", "nc", false, "disable output content coloring (ANSI escape codes)"),
+		flagSet.BoolVarP(&options.DisableChangeLog, "dc", "disable-changelog", false, "disable release changelog in output"),
+	)
+	return flagSet
+}
+
+// configFlags creates the config flags shared by the legacy flags and all commands
+func (options *Options) configFlags(flagSet *goflags.FlagSet) {
+	flagSet.CreateGroup("config", "Config",
+		flagSet.StringVar(&options.ConfigFile, "config", defaultConfigLocation, "cli flag configuration file"),
+		flagSet.StringVarP(&options.Profile, "profile", "pf", "", "configuration profile selecting a tool set and settings (default PDTM_PROFILE or the profile of the config file)"),
+		flagSet.StringVarP(&options.Path, "binary-path", "bp", defaultPath, "custom location to download project binary"),
+		flagSet.StringSliceVarP(&options.Sources, "source", "ts", []string{"api"}, "tool sources in priority order (api, api:<url>, file:<path>, github, github:<org>)", goflags.CommaSeparatedStringSliceOptions),
+		flagSet.IntVarP(&options.Concurrency, "concurrency", "c", defaultConcurrency, "number of projects to install, update or remove concurrently"),
+	)
+}
+
+// networkFlags creates the network flags
+func (options *Options) networkFlags(flagSet *goflags.FlagSet) {
+	flagSet.CreateGroup("network", "Network",
+		flagSet.StringVar(&options.Proxy, "proxy", "", "http, https or socks5 proxy url for all requests (default HTTP_PROXY/HTTPS_PROXY)"),
+		flagSet.StringVarP(&options.CAFile, "ca-file", "ca", "", "PEM file with additional trusted CA certificates"),
+		flagSet.BoolVarP(&options.Insecure, "insecure", "k", false, "disable TLS certificate verification"),
+		flagSet.DurationVar(&options.Timeout, "timeout", 30*time.Second, "timeout for api requests"),
+		flagSet.DurationVarP(&options.ConnectTimeout, "connect-timeout", "ct", 10*time.Second, "timeout for connecting and receiving response headers"),
+		flagSet.StringVar(&options.UserAgent, "user-agent", "pdtm/"+version, "user agent sent with all requests"),
+	)
+}
+
+// githubFlags creates the GitHub flags
+func (options *Options) githubFlags(flagSet *goflags.FlagSet) {
+	flagSet.CreateGroup("github", "GitHub",
+		flagSet.StringVarP(&options.GithubAPIURL, "github-api-url", "gau", "", "GitHub api url, e.g. https://github.example.com/api/v3/ for GitHub Enterprise (default https://api.github.com/)"),
+		flagSet.StringVarP(&options.GithubUploadURL, "github-upload-url", "guu", "", "GitHub uploads url, derived from the api url by default"),
+		flagSet.StringVarP(&options.GithubDownloadURL, "github-download-url", "gdu", "", "url serving public release downloads, derived from the api url by default"),
+		flagSet.StringSliceVarP(&options.GithubTokens, "github-token", "gt", nil, "GitHub token as host=token, a token without host applies to the api url host", goflags.CommaSeparatedStringSliceOptions),
+	)
+}
+
+// outputFlags creates the output flags
+func (options *Options) outputFlags(flagSet *goflags.FlagSet) {
+	flagSet.CreateGroup("output", "Output",
+		flagSet.BoolVar(&options.JSON, "json", false, "write list and operation results as a JSON array to stdout"),
+		flagSet.BoolVarP(&options.JSONL, "jsonl", "jl", false, "write list and operation results as JSON lines to stdout"),
+	)
+}
+
 // configureOutput configures the output on the screen
 func (options *Options) configureOutput() {
 	// If the user desires verbose output, show verbose output
@@ -153,6 +359,43 @@ func (options *Options) configureOutput() {
 	}
 }
 
-func (options *Options) loadConfigFrom(location string) error {
-	return fileutil.Unmarshal(fileutil.YAML, []byte(location), options)
+// configureHTTPClient applies the network options to the shared http client
+func (options *Options) configureHTTPClient() error {
+	err := httpclient.Configure(httpclient.Options{
+		Proxy:          options.Proxy,
+		CAFile:         options.CAFile,
+		Insecure:       options.Insecure,
+		Timeout:        options.Timeout,
+		ConnectTimeout: options.ConnectTimeout,
+		UserAgent:      options.UserAgent,
+	})
+	if err != nil {
+		return err
+	}
+	// the update check keeps its short timeout so it never delays a run
+	updateClient := *httpclient.Client()
+	updateClient.Timeout = updateutils.VersionCheckTimeout
+	updateutils.DefaultHttpClient = &updateClient
+	return nil
+}
+
+// configureGithub applies the GitHub options to the GitHub api clients
+func (options *Options) configureGithub() error {
+	githubOptions := pkg.GithubOptions{
+		APIURL:         options.GithubAPIURL,
+		UploadURL:      options.GithubUploadURL,
+		DownloadURL:    options.GithubDownloadURL,
+		FallbackTokens: options.ConfigGithubTokens,
+	}
+	if len(options.GithubTokens) > 0 {
+		githubOptions.Tokens = make(map[string]string, len(options.GithubTokens))
+		for _, value := range options.GithubTokens {
+			host, token, ok := strings.Cut(value, "=")
+			if !ok {
+				host, token = pkg.GithubHost(options.GithubAPIURL), value
+			}
+			githubOptions.Tokens[host] = token
+		}
+	}
+	return pkg.ConfigureGithub(githubOptions)
 }
@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/receipt"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/utils"
+)
+
+// toolRecord is the machine readable description of a tool
+type toolRecord struct {
+	Name             string            `json:"name"`
+	Repo             string            `json:"repo"`
+	InstalledVersion string            `json:"installed_version,omitempty"`
+	LatestVersion    string            `json:"latest_version"`
+	PinnedVersion    string            `json:"pinned_version,omitempty"`
+	Status           types.ToolStatus  `json:"status"`
+	Path             string            `json:"path,omitempty"`
+	InstallType      types.InstallType `json:"install_type,omitempty"`
+	// Drift is set when the binary changed since pdtm installed it
+	Drift       receipt.Drift `json:"drift,omitempty"`
+	InstalledAt *time.Time    `json:"installed_at,omitempty"`
+}
+
+// newToolRecord describes tool as installed in basePath
+func (r *Runner) newToolRecord(tool types.Tool) toolRecord {
+	installedVersion, status := utils.InstalledStatus(tool, r.options.Path)
+	record := toolRecord{
+		Name:             tool.Name,
+		Repo:             tool.Repo,
+		InstalledVersion: installedVersion,
+		LatestVersion:    tool.Version,
+		Status:           status,
+		InstallType:      tool.InstallType,
+	}
+	if executablePath, exists := path.GetExecutablePath(r.options.Path, tool.Name); exists {
+		record.Path = executablePath
+	}
+	if installed, drift, ok := pkg.InstalledReceipt(r.options.Path, tool.Name); ok {
+		record.Drift, record.InstalledAt = drift, &installed.InstalledAt
+		if drift == receipt.NoDrift {
+			record.InstallType = installed.InstallType
+		}
+	}
+	record.PinnedVersion, _ = r.pins.Get(tool.Name)
+	return record
+}
+
+// jsonOutput reports whether machine readable output was requested
+func (r *Runner) jsonOutput() bool {
+	return r.options.JSON || r.options.JSONL
+}
+
+// writeRecords writes records to stdout as a single JSON array, or as one
+// JSON object per line in JSONL mode
+func writeRecords[T any](records []T, jsonl bool) error {
+	encoder := json.NewEncoder(os.Stdout)
+	if !jsonl {
+		if records == nil {
+			records = []T{}
+		}
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
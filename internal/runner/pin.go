@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	pdtmversion "github.com/projectdiscovery/pdtm/pkg/version"
+)
+
+// splitToolVersion splits a name@version argument into its parts
+func splitToolVersion(arg string) (string, string) {
+	name, version, _ := strings.Cut(arg, "@")
+	return name, strings.TrimPrefix(version, "v")
+}
+
+// resolveTool returns tool at the requested version, falling back to the
+// version it is pinned to. Tools without either stay on the latest release.
+func (r *Runner) resolveTool(tool types.Tool, version string) (types.Tool, error) {
+	if version == "" {
+		r.pinsMutex.Lock()
+		version, _ = r.pins.Get(tool.Name)
+		r.pinsMutex.Unlock()
+	}
+	if version == "" {
+		if tool.Version == "" {
+			// tools given as owner/repo carry no release yet
+			return pkg.GetLatestRelease(tool)
+		}
+		return tool, nil
+	}
+	if !pdtmversion.Equal(version, tool.Version) {
+		release, err := pkg.GetRelease(tool, version)
+		if err != nil {
+			return tool, err
+		}
+		tool = release
+	}
+	tool.Pinned = true
+	return tool, nil
+}
+
+// pin records version as the pinned version of toolName
+func (r *Runner) pin(toolName, version string) {
+	if version == "" {
+		return
+	}
+	r.pinsMutex.Lock()
+	defer r.pinsMutex.Unlock()
+
+	r.pins.Set(toolName, version)
+	if err := r.pins.Save(pinFile); err != nil {
+		gologger.Warning().Msgf("could not save pin for %s: %s", toolName, err)
+		return
+	}
+	gologger.Info().Msgf("pinned %s to %s", toolName, version)
+}
+
+// unpin removes the pinned version of toolName
+func (r *Runner) unpin(toolName string) {
+	r.pinsMutex.Lock()
+	defer r.pinsMutex.Unlock()
+
+	if !r.pins.Delete(toolName) {
+		return
+	}
+	if err := r.pins.Save(pinFile); err != nil {
+		gologger.Warning().Msgf("could not remove pin for %s: %s", toolName, err)
+		return
+	}
+	gologger.Info().Msgf("unpinned %s", toolName)
+}
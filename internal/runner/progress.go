@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/gologger/levels"
+	"github.com/projectdiscovery/gologger/writer"
+	"github.com/projectdiscovery/pdtm/pkg"
+)
+
+const (
+	progressBarWidth    = 20
+	progressNameWidth   = 16
+	progressLogInterval = 10 * time.Second
+)
+
+// configureProgress reports download progress as bars when stderr is a
+// terminal and as periodic log lines otherwise. Nothing is reported in
+// silent mode.
+func configureProgress(options *Options) {
+	if options.Silent {
+		return
+	}
+	if isTerminal(os.Stderr) {
+		bars := &progressBars{out: os.Stderr, log: writer.NewCLI()}
+		gologger.DefaultLogger.SetWriter(bars)
+		pkg.SetProgress(bars)
+		return
+	}
+	pkg.SetProgress(&progressLog{logged: make(map[string]time.Time)})
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressBars renders a bar per active download, i.e. one per worker, below
+// the log output. Log lines are written through it so that the bars are
+// cleared before and redrawn after them.
+type progressBars struct {
+	mutex sync.Mutex
+	out   io.Writer
+	log   writer.Writer
+	bars  []pkg.ProgressEvent
+	drawn int
+}
+
+func (p *progressBars) Report(event pkg.ProgressEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	i := -1
+	for j, bar := range p.bars {
+		if bar.Tool == event.Tool {
+			i = j
+			break
+		}
+	}
+	switch {
+	case event.Done && i >= 0:
+		p.bars = append(p.bars[:i], p.bars[i+1:]...)
+	case event.Done:
+	case i >= 0:
+		p.bars[i] = event
+	default:
+		p.bars = append(p.bars, event)
+	}
+	p.clear()
+	p.draw()
+}
+
+// Write implements writer.Writer for gologger
+func (p *progressBars) Write(data []byte, level levels.Level) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.clear()
+	p.log.Write(data, level)
+	p.draw()
+}
+
+// clear moves the cursor up to the first bar and erases the bars
+func (p *progressBars) clear() {
+	if p.drawn > 0 {
+		_, _ = fmt.Fprintf(p.out, "\x1b[%dA\x1b[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+func (p *progressBars) draw() {
+	for _, bar := range p.bars {
+		_, _ = fmt.Fprintln(p.out, renderProgress(bar, true))
+	}
+	p.drawn = len(p.bars)
+}
+
+// progressLog logs the start, the progress every progressLogInterval and
+// the end of downloads, for output that is not a terminal
+type progressLog struct {
+	mutex  sync.Mutex
+	logged map[string]time.Time
+}
+
+func (p *progressLog) Report(event pkg.ProgressEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	last, started := p.logged[event.Tool]
+	switch {
+	case event.Done:
+		delete(p.logged, event.Tool)
+		// failures are logged with the operation result
+		if event.Err == nil {
+			gologger.Info().Msgf("%s: downloaded %s at %s/s", event.Tool, formatBytes(event.Current), formatBytes(int64(event.Rate)))
+		}
+	case !started:
+		p.logged[event.Tool] = time.Now()
+		size := "unknown size"
+		if event.Total >= 0 {
+			size = formatBytes(event.Total)
+		}
+		gologger.Info().Msgf("%s: downloading %s (%s)", event.Tool, event.Asset, size)
+	case time.Since(last) >= progressLogInterval:
+		p.logged[event.Tool] = time.Now()
+		gologger.Info().Msgf("%s", renderProgress(event, false))
+	}
+}
+
+// renderProgress formats a download as a single line, with a bar when the
+// size is known and withBar is set
+func renderProgress(event pkg.ProgressEvent, withBar bool) string {
+	name := event.Tool
+	if len(name) > progressNameWidth {
+		name = name[:progressNameWidth-1] + "…"
+	}
+	rate := formatBytes(int64(event.Rate)) + "/s"
+	if event.Total <= 0 {
+		return fmt.Sprintf("%-*s %s %s", progressNameWidth, name, formatBytes(event.Current), rate)
+	}
+
+	ratio := float64(event.Current) / float64(event.Total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	sizes := fmt.Sprintf("%3.0f%% %s / %s %s", ratio*100, formatBytes(event.Current), formatBytes(event.Total), rate)
+	if !withBar {
+		return fmt.Sprintf("%s: %s", event.Tool, sizes)
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	return fmt.Sprintf("%-*s [%s] %s", progressNameWidth, name, bar, sizes)
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. 3.2 MB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, units := float64(n)/unit, "KMGT"
+	i := 0
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %cB", value, units[i])
+}
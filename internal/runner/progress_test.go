@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/projectdiscovery/gologger/levels"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+// bufferLog is a gologger writer appending log lines to a shared buffer
+type bufferLog struct {
+	buf *bytes.Buffer
+}
+
+func (b bufferLog) Write(data []byte, level levels.Level) {
+	b.buf.Write(data)
+	b.buf.WriteString("\n")
+}
+
+func TestProgressBars(t *testing.T) {
+	out := &bytes.Buffer{}
+	bars := &progressBars{out: out, log: bufferLog{out}}
+
+	bars.Report(pkg.ProgressEvent{Tool: "nuclei", Total: 2048})
+	bars.Report(pkg.ProgressEvent{Tool: "httpx", Total: -1})
+	require.Equal(t, 2, bars.drawn)
+
+	out.Reset()
+	bars.Write([]byte("installing katana..."), levels.LevelInfo)
+	require.Equal(t, "\x1b[2A\x1b[J"+
+		"installing katana...\n"+
+		renderProgress(pkg.ProgressEvent{Tool: "nuclei", Total: 2048}, true)+"\n"+
+		renderProgress(pkg.ProgressEvent{Tool: "httpx", Total: -1}, true)+"\n", out.String())
+
+	bars.Report(pkg.ProgressEvent{Tool: "nuclei", Current: 2048, Total: 2048, Done: true})
+	bars.Report(pkg.ProgressEvent{Tool: "httpx", Current: 10, Total: -1, Done: true, Err: errors.New("reset")})
+	require.Empty(t, bars.bars)
+	require.Zero(t, bars.drawn)
+}
+
+func TestRenderProgress(t *testing.T) {
+	event := pkg.ProgressEvent{Tool: "nuclei", Current: 5 << 20, Total: 20 << 20, Rate: 1.5 * (1 << 20)}
+	require.Equal(t, "nuclei           [=====               ]  25% 5.0 MB / 20.0 MB 1.5 MB/s", renderProgress(event, true))
+	require.Equal(t, "nuclei:  25% 5.0 MB / 20.0 MB 1.5 MB/s", renderProgress(event, false))
+
+	event = pkg.ProgressEvent{Tool: "interactsh-client", Current: 512, Total: -1, Rate: 256}
+	require.Equal(t, "interactsh-clie… 512 B 256 B/s", renderProgress(event, true))
+}
+
+func TestFormatBytes(t *testing.T) {
+	require.Equal(t, "0 B", formatBytes(0))
+	require.Equal(t, "1023 B", formatBytes(1023))
+	require.Equal(t, "1.0 KB", formatBytes(1024))
+	require.Equal(t, "64.0 MB", formatBytes(64<<20))
+	require.Equal(t, "2.5 GB", formatBytes(5<<29))
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/source"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/utils"
+)
+
+var errNotInList = errors.New("not found in the list")
+
+// releasesCacheTTL is how long the latest releases of tools installed as
+// owner/repo are used before GitHub is asked again
+const releasesCacheTTL = time.Hour
+
+// splitOwnerRepo splits an owner/repo argument into its parts
+func splitOwnerRepo(arg string) (string, string, bool) {
+	owner, repo, ok := strings.Cut(arg, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", false
+	}
+	return owner, repo, true
+}
+
+// lookupTool returns the tool given as name or owner/repo. Repositories
+// missing from the list are returned without a release and reported as
+// unlisted, so they get registered once installed. A repository named like
+// a listed tool is rejected, it would be hidden behind it once registered.
+func lookupTool(toolList []types.Tool, toolArg string) (types.Tool, bool, error) {
+	owner, repo, isRepo := splitOwnerRepo(toolArg)
+	if !isRepo {
+		i, ok := utils.Contains(toolList, toolArg)
+		if !ok {
+			return types.Tool{}, false, errNotInList
+		}
+		return toolList[i], false, nil
+	}
+	for _, tool := range toolList {
+		if strings.EqualFold(tool.GetOwner(), owner) && strings.EqualFold(tool.Repo, repo) {
+			return tool, false, nil
+		}
+	}
+	if i, ok := utils.Contains(toolList, repo); ok {
+		return types.Tool{}, false, fmt.Errorf("%s is already the name of %s/%s", repo, toolList[i].GetOwner(), toolList[i].Repo)
+	}
+	return types.Tool{
+		Name:        repo,
+		Owner:       owner,
+		Repo:        repo,
+		InstallType: types.Binary,
+	}, true, nil
+}
+
+// register records an unlisted tool in the registry file so it is listed,
+// updated and removed like any other tool
+func (r *Runner) register(tool types.Tool, unlisted bool) {
+	if !unlisted {
+		return
+	}
+	r.registryMutex.Lock()
+	defer r.registryMutex.Unlock()
+
+	if err := source.Register(registryFile, tool); err != nil {
+		gologger.Warning().Msgf("could not register %s/%s: %s", tool.GetOwner(), tool.Repo, err)
+		return
+	}
+	gologger.Info().Msgf("registered %s/%s as %s", tool.GetOwner(), tool.Repo, tool.Name)
+}
+
+// unregister removes toolName from the registry file
+func (r *Runner) unregister(toolName string) {
+	r.registryMutex.Lock()
+	defer r.registryMutex.Unlock()
+
+	removed, err := source.Unregister(registryFile, toolName)
+	if err != nil {
+		gologger.Warning().Msgf("could not unregister %s: %s", toolName, err)
+		return
+	}
+	if removed {
+		gologger.Info().Msgf("unregistered %s", toolName)
+	}
+}
@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupTool(t *testing.T) {
+	toolList := []types.Tool{
+		{Name: "nuclei", Repo: "nuclei", Version: "3.1.0"},
+		{Name: "ffuf", Owner: "ffuf", Repo: "ffuf", Version: "2.1.0"},
+	}
+
+	tool, unlisted, err := lookupTool(toolList, "Nuclei")
+	require.NoError(t, err)
+	require.False(t, unlisted)
+	require.Equal(t, "3.1.0", tool.Version)
+
+	tool, unlisted, err = lookupTool(toolList, "projectdiscovery/nuclei")
+	require.NoError(t, err)
+	require.False(t, unlisted)
+	require.Equal(t, "nuclei", tool.Name)
+
+	tool, unlisted, err = lookupTool(toolList, "ffuf/ffuf")
+	require.NoError(t, err)
+	require.False(t, unlisted)
+	require.Equal(t, "2.1.0", tool.Version)
+
+	tool, unlisted, err = lookupTool(toolList, "lc/gau")
+	require.NoError(t, err)
+	require.True(t, unlisted)
+	require.Equal(t, types.Tool{Name: "gau", Owner: "lc", Repo: "gau", InstallType: types.Binary}, tool)
+
+	_, _, err = lookupTool(toolList, "gau")
+	require.ErrorIs(t, err, errNotInList)
+	_, _, err = lookupTool(toolList, "lc/gau/v2")
+	require.ErrorIs(t, err, errNotInList)
+
+	// registering would hide the repository behind the listed tool
+	_, _, err = lookupTool(toolList, "acme/Nuclei")
+	require.EqualError(t, err, "Nuclei is already the name of projectdiscovery/nuclei")
+}
@@ -7,13 +7,18 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/pdtm/pkg"
 	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/pin"
+	"github.com/projectdiscovery/pdtm/pkg/receipt"
+	"github.com/projectdiscovery/pdtm/pkg/source"
 	"github.com/projectdiscovery/pdtm/pkg/types"
 	"github.com/projectdiscovery/pdtm/pkg/utils"
 	errorutil "github.com/projectdiscovery/utils/errors"
+	fileutil "github.com/projectdiscovery/utils/file"
 	stringsutil "github.com/projectdiscovery/utils/strings"
 )
 
@@ -21,18 +26,68 @@ var excludedToolList = []string{"nuclei-templates"}
 
 // Runner contains the internal logic of the program
 type Runner struct {
-	options *Options
+	options       *Options
+	pins          pin.Pins
+	pinsMutex     sync.Mutex
+	registryMutex sync.Mutex
 }
 
 // NewRunner instance
 func NewRunner(options *Options) (*Runner, error) {
+	pins, err := pin.Load(pinFile)
+	if err != nil {
+		return nil, errorutil.NewWithErr(err).Msgf("could not load pinned versions from %s", pinFile)
+	}
+	receipts, err := receipt.Open(receiptsFile)
+	if err != nil {
+		return nil, errorutil.NewWithErr(err).Msgf("could not load install receipts from %s", receiptsFile)
+	}
+	pkg.SetReceipts(receipts)
+	pkg.SetRetention(options.KeepVersions)
+	pkg.SetSkipChecksums(options.SkipChecksum)
+	configureProgress(options)
 	return &Runner{
 		options: options,
+		pins:    pins,
 	}, nil
 }
 
 // Run the instance
 func (r *Runner) Run() error {
+	// check mode only reads, so it runs before anything touches $PATH or the cache
+	if r.isCheck() {
+		toolList, err := r.loadToolList(false)
+		if err != nil {
+			return &ExitError{Code: ExitCodeError, Err: err}
+		}
+		return r.check(toolList)
+	}
+
+	// listing versions only reads as well
+	if len(r.options.ListVersions) > 0 {
+		return r.listVersions(r.options.ListVersions)
+	}
+
+	// so do the info, outdated and doctor commands
+	if r.options.Doctor {
+		return r.doctor()
+	}
+	if len(r.options.Info) > 0 || r.options.Outdated {
+		toolList, err := r.loadToolList(false)
+		if err != nil {
+			return &ExitError{Code: ExitCodeError, Err: err}
+		}
+		if r.options.Outdated {
+			return r.outdated(toolList)
+		}
+		return r.info(toolList, r.options.Info)
+	}
+
+	// the mirror only serves, it never touches $PATH or installed tools
+	if r.options.Serve != "" {
+		return r.serve()
+	}
+
 	// add default path to $PATH
 	if r.options.SetPath || r.options.Path == defaultPath {
 		if err := path.SetENV(r.options.Path); err != nil {
@@ -59,125 +114,294 @@ func (r *Runner) Run() error {
 		}
 	}
 
-	toolListApi, err := utils.FetchToolList()
-	var toolList []types.Tool
+	if r.options.BundleInstall != "" {
+		return r.installBundle()
+	}
 
-	for _, tool := range toolListApi {
-		if !stringsutil.ContainsAny(tool.Name, excludedToolList...) {
-			toolList = append(toolList, tool)
-		}
+	toolList, err := r.loadToolList(true)
+	if err != nil {
+		return err
 	}
 
-	// if toolList is not nil save/update the cache
-	// else fetch from cache file
-	if toolList != nil {
-		go func() {
-			if err := UpdateCache(toolList); err != nil {
-				gologger.Warning().Msgf("%s\n", err)
-			}
-		}()
-	} else {
-		toolList, err = FetchFromCache()
-		if err != nil {
-			return errors.New("pdtm api is down, please try again later")
-		}
-		if toolList != nil {
-			gologger.Warning().Msg("pdtm api is down, using cached information while we fix the issue \n\n")
-		}
+	if r.options.BundleCreate != "" {
+		return r.createBundle(toolList)
 	}
-	if toolList == nil && err != nil {
-		return err
+
+	if r.options.Sync || r.options.UpdateLock {
+		return r.sync(toolList)
 	}
 
 	switch {
 	case r.options.InstallAll:
-		for _, tool := range toolList {
+		for _, tool := range r.selectedTools(toolList) {
 			r.options.Install = append(r.options.Install, tool.Name)
 		}
 	case r.options.UpdateAll:
-		for _, tool := range toolList {
+		for _, tool := range r.selectedTools(toolList) {
 			r.options.Update = append(r.options.Update, tool.Name)
 		}
 	case r.options.RemoveAll:
-		for _, tool := range toolList {
+		for _, tool := range r.selectedTools(toolList) {
 			r.options.Remove = append(r.options.Remove, tool.Name)
 		}
 	}
 	gologger.Verbose().Msgf("using path %s", r.options.Path)
 
-	for _, toolName := range r.options.Install {
-		if !path.IsSubPath(homeDir, r.options.Path) {
-			gologger.Error().Msgf("skipping install outside home folder: %s", toolName)
-			continue
-		}
-		if i, ok := utils.Contains(toolList, toolName); ok {
-			tool := toolList[i]
-			if tool.InstallType == types.Go && isGoInstalled() {
-				if err := pkg.GoInstall(r.options.Path, tool); err != nil {
-					if errors.Is(err, types.ErrIsInstalled) {
-						gologger.Info().Msgf("%s: %s", tool.Name, err)
-					} else {
-						gologger.Error().Msgf("%s: %s", tool.Name, err)
-					}
-				}
-				continue
-			}
+	for _, toolName := range r.options.Unpin {
+		r.unpin(toolName)
+	}
 
-			if err := pkg.Install(r.options.Path, tool); err != nil {
-				if errors.Is(err, types.ErrIsInstalled) {
-					gologger.Info().Msgf("%s: %s", tool.Name, err)
-				} else {
-					gologger.Error().Msgf("error while installing %s: %s", tool.Name, err)
-					gologger.Info().Msgf("trying to install %s using go install", tool.Name)
-					if err := pkg.GoInstall(r.options.Path, tool); err != nil {
-						if errors.Is(err, types.ErrIsInstalled) {
-							gologger.Info().Msgf("%s: %s", tool.Name, err)
-						} else {
-							gologger.Error().Msgf("%s: %s", tool.Name, err)
-						}
-					}
-				}
+	var results []toolResult
+	results = append(results, r.runConcurrently(r.options.Install, func(toolArg string) toolResult {
+		return r.installTool(toolList, toolArg)
+	})...)
+	results = append(results, r.runConcurrently(r.options.Update, func(toolArg string) toolResult {
+		return r.updateTool(toolList, toolArg)
+	})...)
+	results = append(results, r.runConcurrently(r.options.Remove, func(toolArg string) toolResult {
+		return r.removeTool(toolList, toolArg)
+	})...)
+	results = append(results, r.runConcurrently(r.options.Rollback, func(toolArg string) toolResult {
+		return r.rollbackTool(toolList, toolArg)
+	})...)
+	results = append(results, r.runConcurrently(r.options.Use, func(toolArg string) toolResult {
+		return r.useTool(toolList, toolArg)
+	})...)
+	if len(results) > 0 {
+		if r.jsonOutput() {
+			if err := writeRecords(results, r.options.JSONL); err != nil {
+				return err
 			}
 		} else {
-			gologger.Error().Msgf("error while installing %s: %s not found in the list", toolName, toolName)
+			printSummary(results)
 		}
 	}
-	for _, tool := range r.options.Update {
-		if !path.IsSubPath(homeDir, r.options.Path) {
-			gologger.Error().Msgf("skipping update outside home folder: %s", tool)
-			continue
-		}
-		if i, ok := utils.Contains(toolList, tool); ok {
-			if err := pkg.Update(r.options.Path, toolList[i], r.options.DisableChangeLog); err != nil {
-				if err == types.ErrIsUpToDate {
-					gologger.Info().Msgf("%s: %s", tool, err)
-				} else {
-					gologger.Info().Msgf("%s\n", err)
-				}
+
+	if r.options.Prune {
+		r.prune()
+	}
+
+	if len(r.options.Install) == 0 && len(r.options.Update) == 0 && len(r.options.Remove) == 0 && len(r.options.Unpin) == 0 &&
+		len(r.options.Rollback) == 0 && len(r.options.Use) == 0 && !r.options.Prune {
+		return r.ListToolsAndEnv(r.selectedTools(toolList))
+	}
+	return failureExit(results)
+}
+
+// installTool installs a single tool given as name, owner/repo or either with @version
+func (r *Runner) installTool(toolList []types.Tool, toolArg string) toolResult {
+	toolName, version := splitToolVersion(toolArg)
+	result := toolResult{Tool: toolName, Operation: operationInstall}
+	if r.isDisabled(toolName) {
+		gologger.Info().Msgf("%s: disabled in config, skipping install", toolName)
+		return result.skipped("disabled in config")
+	}
+	if !path.IsSubPath(homeDir, r.options.Path) {
+		gologger.Error().Msgf("skipping install outside home folder: %s", toolName)
+		return result.skipped("outside home folder")
+	}
+	tool, unlisted, err := lookupTool(toolList, toolName)
+	if err != nil {
+		gologger.Error().Msgf("error while installing %s: %s", toolName, err)
+		return result.failed(err)
+	}
+	tool, err = r.resolveTool(tool, version)
+	if err != nil {
+		gologger.Error().Msgf("error while installing %s: could not find release %s: %s", toolName, version, err)
+		return result.failed(err)
+	}
+	result.Version = tool.Version
+
+	if tool.InstallType == types.Go && isGoInstalled() {
+		if err := pkg.GoInstall(r.options.Path, tool); err != nil {
+			if errors.Is(err, types.ErrIsInstalled) {
+				gologger.Info().Msgf("%s: %s", tool.Name, err)
+				return result.skipped(err.Error())
 			}
+			gologger.Error().Msgf("%s: %s", tool.Name, err)
+			return result.failed(err)
 		}
+		r.register(tool, unlisted)
+		r.pin(tool.Name, version)
+		return result.succeeded()
 	}
-	for _, tool := range r.options.Remove {
-		if !path.IsSubPath(homeDir, r.options.Path) {
-			gologger.Error().Msgf("skipping remove outside home folder: %s", tool)
-			continue
+
+	if err := pkg.Install(r.options.Path, tool); err != nil {
+		if errors.Is(err, types.ErrIsInstalled) {
+			gologger.Info().Msgf("%s: %s", tool.Name, err)
+			return result.skipped(err.Error())
 		}
-		if i, ok := utils.Contains(toolList, tool); ok {
-			if err := pkg.Remove(r.options.Path, toolList[i]); err != nil {
-				var notFoundError *exec.Error
-				if errors.As(err, &notFoundError) {
-					gologger.Info().Msgf("%s: not found", tool)
-				} else {
-					gologger.Info().Msgf("%s\n", err)
-				}
+		// an asset that is tampered with or can not be verified must not be
+		// papered over by building from source
+		if isChecksumError(err) {
+			gologger.Error().Msgf("%s: %s, not falling back to go install", tool.Name, err)
+			return result.failed(err)
+		}
+		gologger.Error().Msgf("error while installing %s: %s", tool.Name, err)
+		gologger.Info().Msgf("trying to install %s using go install", tool.Name)
+		if err := pkg.GoInstall(r.options.Path, tool); err != nil {
+			if errors.Is(err, types.ErrIsInstalled) {
+				gologger.Info().Msgf("%s: %s", tool.Name, err)
+				return result.skipped(err.Error())
 			}
+			gologger.Error().Msgf("%s: %s", tool.Name, err)
+			return result.failed(err)
+		}
+	}
+	r.register(tool, unlisted)
+	r.pin(tool.Name, version)
+	return result.succeeded()
+}
+
+// isChecksumError reports whether err is a failed or impossible verification
+// of a release asset
+func isChecksumError(err error) bool {
+	var mismatch *types.ChecksumMismatchError
+	var unavailable *types.ChecksumUnavailableError
+	return errors.As(err, &mismatch) || errors.As(err, &unavailable)
+}
+
+// updateTool updates a single tool given as name, owner/repo or either with @version
+func (r *Runner) updateTool(toolList []types.Tool, toolArg string) toolResult {
+	toolName, version := splitToolVersion(toolArg)
+	result := toolResult{Tool: toolName, Operation: operationUpdate}
+	if r.isDisabled(toolName) {
+		gologger.Info().Msgf("%s: disabled in config, skipping update", toolName)
+		return result.skipped("disabled in config")
+	}
+	if !path.IsSubPath(homeDir, r.options.Path) {
+		gologger.Error().Msgf("skipping update outside home folder: %s", toolName)
+		return result.skipped("outside home folder")
+	}
+	tool, unlisted, err := lookupTool(toolList, toolName)
+	if err != nil {
+		gologger.Error().Msgf("error while updating %s: %s", toolName, err)
+		return result.failed(err)
+	}
+	if _, exists := path.GetExecutablePath(r.options.Path, tool.Name); !exists {
+		gologger.Verbose().Msgf("%s: not installed, skipping update", toolName)
+		return result.skipped("not installed")
+	}
+	tool, err = r.resolveTool(tool, version)
+	if err != nil {
+		gologger.Error().Msgf("error while updating %s: could not find release %s: %s", toolName, version, err)
+		return result.failed(err)
+	}
+	result.Version = tool.Version
+
+	if err := pkg.Update(r.options.Path, tool, r.options.DisableChangeLog); err != nil {
+		if err == types.ErrIsUpToDate || err == types.ErrIsNewer {
+			if tool.Pinned {
+				gologger.Info().Msgf("%s: %s (pinned to %s)", toolName, err, tool.Version)
+				r.pin(tool.Name, version)
+			} else {
+				gologger.Info().Msgf("%s: %s", toolName, err)
+			}
+			return result.skipped(err.Error())
+		}
+		gologger.Error().Msgf("%s: %s", toolName, err)
+		return result.failed(err)
+	}
+	r.register(tool, unlisted)
+	r.pin(tool.Name, version)
+	return result.succeeded()
+}
 
+// removeTool removes a single tool given as name or owner/repo, or a single
+// inactive version of it given with @version
+func (r *Runner) removeTool(toolList []types.Tool, toolArg string) toolResult {
+	toolName, version := splitToolVersion(toolArg)
+	result := toolResult{Tool: toolName, Operation: operationRemove, Version: version}
+	if !path.IsSubPath(homeDir, r.options.Path) {
+		gologger.Error().Msgf("skipping remove outside home folder: %s", toolName)
+		return result.skipped("outside home folder")
+	}
+	tool, _, err := lookupTool(toolList, toolName)
+	if err != nil {
+		gologger.Error().Msgf("error while removing %s: %s", toolName, err)
+		return result.failed(err)
+	}
+	// a receipt is left behind when the binary was deleted outside of pdtm
+	_, exists := path.GetEntryPath(r.options.Path, tool.Name)
+	if _, _, hasReceipt := pkg.InstalledReceipt(r.options.Path, tool.Name); !exists && !hasReceipt {
+		gologger.Verbose().Msgf("%s: not installed, skipping remove", toolName)
+		return result.skipped("not installed")
+	}
+	if version != "" {
+		if err := pkg.RemoveVersion(r.options.Path, tool, version); err != nil {
+			gologger.Error().Msgf("%s: %s", toolName, err)
+			return result.failed(err)
 		}
+		return result.succeeded()
 	}
-	if len(r.options.Install) == 0 && len(r.options.Update) == 0 && len(r.options.Remove) == 0 {
-		return r.ListToolsAndEnv(toolList)
+	if err := pkg.Remove(r.options.Path, tool); err != nil {
+		gologger.Error().Msgf("%s: %s", toolName, err)
+		return result.failed(err)
 	}
-	return nil
+	r.unpin(tool.Name)
+	r.unregister(tool.Name)
+	return result.succeeded()
+}
+
+// loadToolList merges the tool lists of the configured sources, falling back
+// to the cache file when none of them is available. The cache is refreshed when updateCache is set.
+func (r *Runner) loadToolList(updateCache bool) ([]types.Tool, error) {
+	var sources []source.ToolSource
+	for _, spec := range r.options.Sources {
+		toolSource, err := source.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, toolSource)
+	}
+	if len(sources) == 0 {
+		sources = append(sources, &source.API{})
+	}
+	// tools installed as owner/repo come last so listed tools take priority
+	if fileutil.FileExists(registryFile) {
+		// check mode never modifies anything, including the release cache
+		releases := &source.ReleaseCache{Path: releasesCacheFile, TTL: releasesCacheTTL, ReadOnly: r.isCheck()}
+		sources = append(sources, &source.File{Path: registryFile, Releases: releases})
+	}
+	toolListSources, err := source.Merge(sources...)
+	if err != nil && toolListSources != nil {
+		// partial results are usable, report the sources that failed
+		gologger.Warning().Msgf("could not load tool source: %s", err)
+	}
+	var toolList []types.Tool
+
+	for _, tool := range toolListSources {
+		if !stringsutil.ContainsAny(tool.Name, excludedToolList...) {
+			toolList = append(toolList, tool)
+		}
+	}
+
+	// if toolList is not nil save/update the cache
+	// else fetch from cache file
+	if toolList != nil {
+		if updateCache {
+			go func() {
+				if err := UpdateCache(toolList); err != nil {
+					gologger.Warning().Msgf("%s\n", err)
+				}
+			}()
+		}
+	} else {
+		toolList, err = FetchFromCache()
+		if err != nil {
+			return nil, errors.New("pdtm tool sources are unavailable, please try again later")
+		}
+		if toolList != nil {
+			gologger.Warning().Msg("pdtm tool sources are unavailable, using cached information while we fix the issue \n\n")
+		}
+	}
+	if toolList == nil && err != nil {
+		return nil, err
+	}
+	if err := applyFilesManifest(filesManifestFile, toolList); err != nil {
+		gologger.Warning().Msgf("could not read %s: %s", filesManifestFile, err)
+	}
+	return toolList, nil
 }
 
 func getGoEnv(key string) string {
@@ -221,6 +445,14 @@ func FetchFromCache() ([]types.Tool, error) {
 
 // ListToolsAndEnv prints the list of tools
 func (r *Runner) ListToolsAndEnv(tools []types.Tool) error {
+	if r.jsonOutput() {
+		records := make([]toolRecord, 0, len(tools))
+		for _, tool := range tools {
+			records = append(records, r.newToolRecord(tool))
+		}
+		return writeRecords(records, r.options.JSONL)
+	}
+
 	gologger.Info().Msgf("%s\n", path.GetOsData())
 	gologger.Info().Msgf("Path to download project binary: %s\n", r.options.Path)
 	var fmtMsg string
@@ -233,6 +465,12 @@ func (r *Runner) ListToolsAndEnv(tools []types.Tool) error {
 
 	for i, tool := range tools {
 		msg := utils.InstalledVersion(tool, r.options.Path, au)
+		if _, drift, ok := pkg.InstalledReceipt(r.options.Path, tool.Name); ok && drift != receipt.NoDrift {
+			msg += fmt.Sprintf(" (%s since install)", au.BrightRed(drift).String())
+		}
+		if version, ok := r.pins.Get(tool.Name); ok {
+			msg += fmt.Sprintf(" (%s %s)", au.BrightCyan("pinned").String(), version)
+		}
 		fmt.Printf("%d. %s %s\n", i+1, tool.Name, msg)
 	}
 	return nil
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/projectdiscovery/goflags"
+	"github.com/projectdiscovery/pdtm/pkg"
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallWithoutChecksumsSkipsGoInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go needs a shell")
+	}
+	root := t.TempDir()
+	original := homeDir
+	homeDir = root
+	t.Cleanup(func() { homeDir = original })
+
+	// go install leaves a mark when it runs
+	goBin := t.TempDir()
+	mark := filepath.Join(root, "go-install-ran")
+	require.NoError(t, os.WriteFile(filepath.Join(goBin, "go"), []byte("#!/bin/sh\ntouch '"+mark+"'\n"), 0755))
+	t.Setenv("PATH", goBin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// the release publishes no checksums file
+	tool := types.Tool{Name: "dnsx", Repo: "dnsx", Version: "1.1.1", InstallType: types.Binary, Assets: map[string]string{
+		"dnsx_1.1.1_" + ospath.CheckOS() + ".zip": "1",
+	}}
+	r := &Runner{options: &Options{Path: filepath.Join(root, "bin")}}
+	result := r.installTool([]types.Tool{tool}, "dnsx")
+	require.Equal(t, statusFailed, result.Status)
+	var unavailableErr *types.ChecksumUnavailableError
+	require.ErrorAs(t, result.Err, &unavailableErr)
+	require.NoFileExists(t, mark)
+}
+
+func TestCheckLeavesConfigUntouched(t *testing.T) {
+	configDir := t.TempDir()
+	originalRegistry, originalReleases := registryFile, releasesCacheFile
+	registryFile = filepath.Join(configDir, "tools.yaml")
+	releasesCacheFile = filepath.Join(configDir, "releases.json")
+	t.Cleanup(func() { registryFile, releasesCacheFile = originalRegistry, originalReleases })
+	require.NoError(t, os.WriteFile(registryFile, []byte("tools:\n  - name: scanner\n    owner: acme\n    repo: scanner\n"), 0644))
+
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/acme/scanner/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, `{"tag_name":"v1.0.0","assets":[{"id":1,"name":"scanner_1.0.0_linux_amd64.tar.gz"}]}`)
+	}))
+	t.Cleanup(github.Close)
+	require.NoError(t, pkg.ConfigureGithub(pkg.GithubOptions{APIURL: github.URL}))
+	t.Cleanup(func() {
+		require.NoError(t, pkg.ConfigureGithub(pkg.GithubOptions{}))
+	})
+
+	listFile := filepath.Join(t.TempDir(), "list.yaml")
+	require.NoError(t, os.WriteFile(listFile, []byte("tools: []\n"), 0644))
+	r := &Runner{options: &Options{
+		CheckAll: true,
+		Path:     filepath.Join(t.TempDir(), "bin"),
+		Sources:  goflags.StringSlice{"file:" + listFile},
+	}}
+	var exitErr *ExitError
+	require.ErrorAs(t, r.Run(), &exitErr)
+	require.Equal(t, ExitCodeMissing, exitErr.Code)
+
+	entries, err := os.ReadDir(configDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "check mode must not write to the config directory")
+}
+
+func TestSyncRemovesOnlyWithExact(t *testing.T) {
+	root := t.TempDir()
+	original := homeDir
+	homeDir = root
+	t.Cleanup(func() { homeDir = original })
+
+	// httpx was installed by hand, the manifest lists no project
+	binPath := filepath.Join(root, "bin")
+	require.NoError(t, os.MkdirAll(binPath, os.ModePerm))
+	binary := filepath.Join(binPath, "httpx")
+	require.NoError(t, os.WriteFile(binary, []byte("httpx"), 0755))
+	project := t.TempDir()
+	manifestFile := filepath.Join(project, "pdtm.yaml")
+	require.NoError(t, os.WriteFile(manifestFile, []byte("tools: []\n"), 0644))
+	toolList := []types.Tool{{Name: "httpx", Repo: "httpx"}}
+
+	options := &Options{Path: binPath, Sync: true, Manifest: manifestFile, Lockfile: filepath.Join(project, "pdtm.lock")}
+	r := &Runner{options: options}
+	require.NoError(t, r.sync(toolList))
+	require.FileExists(t, binary)
+
+	options.SyncExact = true
+	require.NoError(t, r.sync(toolList))
+	require.NoFileExists(t, binary)
+}
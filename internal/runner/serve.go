@@ -0,0 +1,29 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/projectdiscovery/pdtm/pkg/mirror"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// serve runs a mirror of the tool sources until interrupted
+func (r *Runner) serve() error {
+	server, err := mirror.New(mirror.Options{
+		Tools: func() ([]types.Tool, error) {
+			return r.loadToolList(false)
+		},
+		Dir:     r.options.ServeDir,
+		Refresh: r.options.ServeRefresh,
+		URL:     r.options.ServeURL,
+	})
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return server.ListenAndServe(ctx, r.options.Serve)
+}
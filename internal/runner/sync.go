@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/manifest"
+	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	pdtmversion "github.com/projectdiscovery/pdtm/pkg/version"
+	errorutil "github.com/projectdiscovery/utils/errors"
+)
+
+// sync makes the binary path match the lockfile. The manifest is resolved
+// into a new lockfile first when there is none, when it no longer matches
+// the manifest or when an update was requested. Installed tools missing
+// from the lockfile are only removed with -exact.
+func (r *Runner) sync(toolList []types.Tool) error {
+	projectManifest, err := manifest.LoadManifest(r.options.Manifest)
+	if err != nil {
+		return errorutil.NewWithErr(err).Msgf("could not read manifest %s", r.options.Manifest)
+	}
+	lockfile, err := manifest.LoadLockfile(r.options.Lockfile)
+	if err != nil && !os.IsNotExist(err) {
+		return errorutil.NewWithErr(err).Msgf("could not read lockfile %s", r.options.Lockfile)
+	}
+
+	if lockfile == nil || r.options.UpdateLock || !lockfile.Satisfies(projectManifest) {
+		gologger.Info().Msgf("resolving %s...", r.options.Manifest)
+		lockfile, err = manifest.Resolve(projectManifest, toolList)
+		if err != nil {
+			return err
+		}
+		if err := lockfile.Save(r.options.Lockfile); err != nil {
+			return errorutil.NewWithErr(err).Msgf("could not write lockfile %s", r.options.Lockfile)
+		}
+		gologger.Info().Msgf("wrote %s", r.options.Lockfile)
+	}
+	if !r.options.Sync {
+		return nil
+	}
+
+	if !path.IsSubPath(homeDir, r.options.Path) {
+		return fmt.Errorf("skipping sync outside home folder: %s", r.options.Path)
+	}
+	gologger.Verbose().Msgf("using path %s", r.options.Path)
+
+	var failed []string
+	locked := make(map[string]struct{}, len(lockfile.Tools))
+	for _, lockedTool := range lockfile.Tools {
+		tool := lockedTool.Tool()
+		locked[strings.ToLower(tool.Name)] = struct{}{}
+		if err := r.syncTool(tool); err != nil {
+			gologger.Error().Msgf("error while syncing %s: %s", tool.Name, err)
+			failed = append(failed, tool.Name)
+		}
+	}
+
+	// installed tools outside of the lockfile may have been installed by
+	// hand, they are only removed when asked for with -exact
+	var extra []types.Tool
+	for _, tool := range toolList {
+		if _, ok := locked[strings.ToLower(tool.Name)]; ok {
+			continue
+		}
+		if _, exists := path.GetExecutablePath(r.options.Path, tool.Name); exists {
+			extra = append(extra, tool)
+		}
+	}
+	if len(extra) > 0 && !r.options.SyncExact {
+		names := make([]string, 0, len(extra))
+		for _, tool := range extra {
+			names = append(names, tool.Name)
+		}
+		gologger.Info().Msgf("not in the lockfile: %s, sync with -exact to remove them", strings.Join(names, ", "))
+		extra = nil
+	}
+	for _, tool := range extra {
+		if err := pkg.Remove(r.options.Path, tool); err != nil {
+			gologger.Error().Msgf("error while removing %s: %s", tool.Name, err)
+			failed = append(failed, tool.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not sync %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// syncTool installs tool at the locked version, replacing any other version
+func (r *Runner) syncTool(tool types.Tool) error {
+	if _, exists := path.GetExecutablePath(r.options.Path, tool.Name); !exists {
+		if tool.InstallType == types.Go && isGoInstalled() {
+			return pkg.GoInstall(r.options.Path, tool)
+		}
+		return pkg.Install(r.options.Path, tool)
+	}
+
+	installedVersion, err := pkg.InstalledVersion(r.options.Path, tool)
+	if err == nil && pdtmversion.Equal(installedVersion, tool.Version) {
+		gologger.Info().Msgf("%s: %s is in sync", tool.Name, tool.Version)
+		return nil
+	}
+	if err := pkg.Update(r.options.Path, tool, r.options.DisableChangeLog); err != nil && !errors.Is(err, types.ErrIsUpToDate) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// versionRecord is the machine readable description of an installed version
+type versionRecord struct {
+	Tool string `json:"tool"`
+	pkg.ToolVersion
+	// LocalFile is the .pdtm-version file selecting the version in the
+	// current directory
+	LocalFile string `json:"local_file,omitempty"`
+}
+
+// useTool makes the version given as name@version the active version of a
+// tool, installing it side by side when missing, and pins it
+func (r *Runner) useTool(toolList []types.Tool, toolArg string) toolResult {
+	toolName, version := splitToolVersion(toolArg)
+	result := toolResult{Tool: toolName, Operation: operationUse, Version: version}
+	if r.isDisabled(toolName) {
+		gologger.Info().Msgf("%s: disabled in config, skipping use", toolName)
+		return result.skipped("disabled in config")
+	}
+	if !path.IsSubPath(homeDir, r.options.Path) {
+		gologger.Error().Msgf("skipping use outside home folder: %s", toolName)
+		return result.skipped("outside home folder")
+	}
+	if version == "" {
+		err := fmt.Errorf("no version given, use %s@<version>", toolName)
+		gologger.Error().Msgf("%s: %s", toolName, err)
+		return result.failed(err)
+	}
+	tool, unlisted, err := lookupTool(toolList, toolName)
+	if err != nil {
+		gologger.Error().Msgf("error while using %s: %s", toolName, err)
+		return result.failed(err)
+	}
+	// installed versions are switched to without looking up their release
+	if _, exists := path.VersionExecutablePath(r.options.Path, tool.Name, version); exists {
+		tool.Version, tool.Pinned = version, true
+	} else if tool, err = r.resolveTool(tool, version); err != nil {
+		gologger.Error().Msgf("error while using %s: could not find release %s: %s", toolName, version, err)
+		return result.failed(err)
+	}
+	if err := pkg.Use(r.options.Path, tool); err != nil {
+		gologger.Error().Msgf("%s: %s", toolName, err)
+		return result.failed(err)
+	}
+	r.register(tool, unlisted)
+	r.pin(tool.Name, version)
+	return result.succeeded()
+}
+
+// rollbackTool switches a single tool given as name or name@version back to
+// another installed version and pins it there, so updating all tools does
+// not bring the bad release back
+func (r *Runner) rollbackTool(toolList []types.Tool, toolArg string) toolResult {
+	toolName, version := splitToolVersion(toolArg)
+	result := toolResult{Tool: toolName, Operation: operationRollback}
+	if !path.IsSubPath(homeDir, r.options.Path) {
+		gologger.Error().Msgf("skipping rollback outside home folder: %s", toolName)
+		return result.skipped("outside home folder")
+	}
+	tool, _, err := lookupTool(toolList, toolName)
+	if err != nil {
+		gologger.Error().Msgf("error while rolling back %s: %s", toolName, err)
+		return result.failed(err)
+	}
+	if _, exists := path.GetExecutablePath(r.options.Path, tool.Name); !exists {
+		gologger.Verbose().Msgf("%s: not installed, skipping rollback", toolName)
+		return result.skipped("not installed")
+	}
+	restored, err := pkg.Rollback(r.options.Path, tool, version)
+	if err != nil {
+		if errors.Is(err, types.ErrIsUpToDate) {
+			gologger.Info().Msgf("%s: already at %s", tool.Name, version)
+			return result.skipped("already at " + version)
+		}
+		gologger.Error().Msgf("%s: %s", tool.Name, err)
+		return result.failed(err)
+	}
+	result.Version = restored
+	gologger.Info().Msgf("rolled back %s to %s", tool.Name, restored)
+	r.pin(tool.Name, restored)
+	return result.succeeded()
+}
+
+// listVersions prints the versions of the given tools installed side by side
+func (r *Runner) listVersions(toolNames []string) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	var records []versionRecord
+	for _, toolName := range toolNames {
+		versions, err := pkg.Versions(r.options.Path, toolName)
+		if err != nil {
+			return err
+		}
+		localVersion, localFile, hasLocal := pkg.LocalVersion(dir, toolName)
+		if !r.jsonOutput() {
+			if len(versions) == 0 {
+				fmt.Printf("%s: no versions installed side by side\n", toolName)
+				continue
+			}
+			fmt.Printf("%s:\n", toolName)
+		}
+		for _, tv := range versions {
+			record := versionRecord{Tool: toolName, ToolVersion: tv}
+			if hasLocal && tv.Version == localVersion {
+				record.LocalFile = localFile
+			}
+			records = append(records, record)
+			if !r.jsonOutput() {
+				fmt.Printf("  %s%s\n", tv.Version, versionLabels(record))
+			}
+		}
+	}
+	if r.jsonOutput() {
+		return writeRecords(records, r.options.JSONL)
+	}
+	return nil
+}
+
+// versionLabels describes the state of an installed version for -list-versions
+func versionLabels(record versionRecord) string {
+	var labels []string
+	if record.Active {
+		labels = append(labels, au.BrightGreen("active").String())
+	}
+	if record.Held {
+		labels = append(labels, au.BrightCyan("held").String())
+	}
+	if record.LocalFile != "" {
+		labels = append(labels, "selected by "+record.LocalFile)
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(labels, ", ") + ")"
+}
+
+// prune removes the inactive versions beyond the retention of every tool
+func (r *Runner) prune() {
+	toolNames, err := pkg.VersionedTools(r.options.Path)
+	if err != nil {
+		gologger.Error().Msgf("could not read installed versions: %s", err)
+		return
+	}
+	var total int64
+	for _, toolName := range toolNames {
+		reclaimed, err := pkg.Prune(r.options.Path, toolName, r.options.KeepVersions)
+		if err != nil {
+			gologger.Error().Msgf("%s: could not prune inactive versions: %s", toolName, err)
+		}
+		if reclaimed > 0 {
+			gologger.Verbose().Msgf("%s: pruned inactive versions, reclaimed %s", toolName, formatBytes(reclaimed))
+		}
+		total += reclaimed
+	}
+	gologger.Info().Msgf("pruned inactive versions beyond the last %d, reclaimed %s", r.options.KeepVersions, formatBytes(total))
+}
@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/projectdiscovery/gologger"
+	syncutil "github.com/projectdiscovery/utils/sync"
+)
+
+const defaultConcurrency = 4
+
+type operation string
+
+const (
+	operationInstall  operation = "install"
+	operationUpdate   operation = "update"
+	operationRemove   operation = "remove"
+	operationRollback operation = "rollback"
+	operationUse      operation = "use"
+)
+
+type resultStatus string
+
+const (
+	statusSucceeded resultStatus = "succeeded"
+	statusFailed    resultStatus = "failed"
+	statusSkipped   resultStatus = "skipped"
+)
+
+// toolResult is the outcome of an operation on a single tool
+type toolResult struct {
+	Tool      string       `json:"tool"`
+	Operation operation    `json:"operation"`
+	Version   string       `json:"version,omitempty"`
+	Status    resultStatus `json:"status"`
+	Reason    string       `json:"reason,omitempty"`
+	Err       error        `json:"-"`
+}
+
+func (t toolResult) succeeded() toolResult {
+	t.Status = statusSucceeded
+	return t
+}
+
+func (t toolResult) skipped(reason string) toolResult {
+	t.Status, t.Reason = statusSkipped, reason
+	return t
+}
+
+func (t toolResult) failed(err error) toolResult {
+	t.Status, t.Reason, t.Err = statusFailed, err.Error(), err
+	return t
+}
+
+// runConcurrently runs fn for every tool on a bounded pool of workers and
+// returns the results in the order the tools were given. A failing tool
+// does not stop the others.
+func (r *Runner) runConcurrently(tools []string, fn func(tool string) toolResult) []toolResult {
+	results := make([]toolResult, len(tools))
+	if len(tools) == 0 {
+		return results
+	}
+
+	concurrency := r.options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	wg, err := syncutil.New(syncutil.WithSize(concurrency))
+	if err != nil {
+		// unreachable with a positive size, fall back to running sequentially
+		for i, tool := range tools {
+			results[i] = fn(tool)
+		}
+		return results
+	}
+	for i, tool := range tools {
+		wg.Add()
+		go func(i int, tool string) {
+			defer wg.Done()
+			results[i] = fn(tool)
+		}(i, tool)
+	}
+	wg.Wait()
+	return results
+}
+
+// failureExit returns an ExitError with ExitCodeError when any of results
+// failed, so that scripts can tell a partial failure from success
+func failureExit(results []toolResult) error {
+	for _, result := range results {
+		if result.Status == statusFailed {
+			return &ExitError{Code: ExitCodeError}
+		}
+	}
+	return nil
+}
+
+// printSummary prints a table of the operation results followed by totals
+func printSummary(results []toolResult) {
+	counts := make(map[resultStatus]int)
+	builder := &strings.Builder{}
+	table := tabwriter.NewWriter(builder, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "TOOL\tOPERATION\tVERSION\tSTATUS\tDETAILS")
+	for _, result := range results {
+		counts[result.Status]++
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", result.Tool, result.Operation, valueOrDash(result.Version), result.Status, valueOrDash(result.Reason))
+	}
+	_ = table.Flush()
+
+	gologger.Print().Msgf("\n%s\n%d succeeded, %d failed, %d skipped\n", builder.String(), counts[statusSucceeded], counts[statusFailed], counts[statusSkipped])
+}
+
+func valueOrDash(value string) string {
+	// keep multi-line errors (e.g. go install output) from breaking the table
+	value, _, _ = strings.Cut(value, "\n")
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	r := &Runner{options: &Options{Concurrency: 2}}
+	tools := []string{"nuclei", "httpx", "dnsx", "naabu", "katana"}
+
+	var running, maxRunning int32
+	results := r.runConcurrently(tools, func(tool string) toolResult {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			observed := atomic.LoadInt32(&maxRunning)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		result := toolResult{Tool: tool, Operation: operationInstall}
+		if tool == "httpx" {
+			return result.failed(errors.New("download failed"))
+		}
+		return result.succeeded()
+	})
+
+	require.LessOrEqual(t, maxRunning, int32(2), "pool should be bounded by concurrency")
+	require.Len(t, results, len(tools))
+	for i, tool := range tools {
+		require.Equal(t, tool, results[i].Tool, "results should keep the input order")
+	}
+	require.Equal(t, statusFailed, results[1].Status)
+	require.Equal(t, statusSucceeded, results[4].Status, "a failure should not stop other workers")
+
+	var exitErr *ExitError
+	require.ErrorAs(t, failureExit(results), &exitErr)
+	require.Equal(t, ExitCodeError, exitErr.Code)
+	require.NoError(t, failureExit(results[2:]))
+}
@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/asset"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	osutils "github.com/projectdiscovery/utils/os"
+	"github.com/ulikunitz/xz"
+)
+
+// extract writes the binary of tool contained in an asset of the given
+// format to path. Archive entries matching the auxiliary files of tool are
+// staged alongside it, see stageFile.
+func extract(format asset.Format, reader io.Reader, tool types.Tool, path string) error {
+	switch format {
+	case asset.Zip:
+		return extractZip(reader, tool, path)
+	case asset.TarGz:
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		return extractTar(gzipReader, tool, path)
+	case asset.TarXz:
+		xzReader, err := xz.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		return extractTar(xzReader, tool, path)
+	case asset.TarZst:
+		zstdReader, err := zstd.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer zstdReader.Close()
+		return extractTar(zstdReader, tool, path)
+	case asset.Gz:
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		return writeBinary(gzipReader, tool.Name, path)
+	case asset.Binary:
+		return writeBinary(reader, tool.Name, path)
+	default:
+		return fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+// extractEntry writes the archive entry name to path when it is the binary
+// of tool, or stages it when it is one of its auxiliary files. Completions
+// are often named after the binary, so an entry named like the binary is
+// only an auxiliary file when matched by a pattern naming its directory,
+// e.g. completions/*. Broad patterns such as * never claim the binary.
+func extractEntry(reader io.Reader, name string, tool types.Tool, path string) error {
+	i, isFile := matchFile(tool, name)
+	if isToolBinary(name, tool.Name) && (!isFile || !strings.Contains(tool.Files[i].Pattern, "/")) {
+		return writeFile(reader, filepath.Join(path, filepath.Base(name)), 0755)
+	}
+	if isFile {
+		return writeFile(reader, stagedFilePath(path, i, name), 0644)
+	}
+	return nil
+}
+
+// isToolBinary reports whether the archive entry name is the toolName binary
+func isToolBinary(name, toolName string) bool {
+	return strings.EqualFold(strings.TrimSuffix(filepath.Base(name), extIfFound), toolName)
+}
+
+// writeBinary writes an uncompressed binary to path under the tool name
+func writeBinary(reader io.Reader, toolName, path string) error {
+	name := toolName
+	if osutils.IsWindows() {
+		name += extIfFound
+	}
+	return writeFile(reader, filepath.Join(path, name), 0755)
+}
+
+// writeFile writes reader to a file with the given mode at filePath
+func writeFile(reader io.Reader, filePath string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return err
+	}
+	dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, reader); err != nil {
+		_ = dstFile.Close()
+		return err
+	}
+	if err := dstFile.Close(); err != nil {
+		return err
+	}
+	// the mode passed to OpenFile is masked by the umask
+	return os.Chmod(filePath, mode)
+}
+
+// extractTar extracts the binary and auxiliary files of tool from an
+// uncompressed tar stream
+func extractTar(reader io.Reader, tool types.Tool, path string) error {
+	tarReader := tar.NewReader(reader)
+	// iterate through the files in the archive
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		if err := extractEntry(tarReader, header.Name, tool, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZip extracts the binary and auxiliary files of tool from a zip archive. Zip archives
+// are read from their central directory at the end, so the download is
+// spooled to a temporary file in path to keep memory use independent of the
+// archive size.
+func extractZip(reader io.Reader, tool types.Tool, path string) error {
+	spool, err := os.CreateTemp(path, ".download-*.zip")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := spool.Close(); err != nil {
+			gologger.Warning().Msgf("%s: error closing file: %s", tool.Name, err)
+		}
+		if err := os.Remove(spool.Name()); err != nil {
+			gologger.Warning().Msgf("%s: error removing file: %s", tool.Name, err)
+		}
+	}()
+	size, err := io.Copy(spool, reader)
+	if err != nil {
+		return err
+	}
+	zipReader, err := zip.NewReader(spool, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zipReader.File {
+		if !f.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		if _, ok := matchFile(tool, f.Name); !ok && !isToolBinary(f.Name, tool.Name) {
+			continue
+		}
+		fileInArchive, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = extractEntry(fileInArchive, f.Name, tool, path)
+		if closeErr := fileInArchive.Close(); closeErr != nil {
+			gologger.Warning().Msgf("%s: error closing file in archive: %s", tool.Name, closeErr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
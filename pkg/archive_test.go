@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/projectdiscovery/pdtm/pkg/asset"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+// tarArchive returns an uncompressed tar holding the binary under a release
+// directory, next to files that must not be extracted
+func tarArchive(t *testing.T, name, content string) []byte {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		name += extIfFound
+	}
+	buf := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buf)
+	for _, file := range []struct{ name, content string }{
+		{"release/README.md", "readme"},
+		{"release/" + name, content},
+	} {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content))}))
+		_, err := tarWriter.Write([]byte(file.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	return buf.Bytes()
+}
+
+func compress(t *testing.T, data []byte, newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	writer, err := newWriter(buf)
+	require.NoError(t, err)
+	_, err = writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func gzipWriter(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+func xzWriter(w io.Writer) (io.WriteCloser, error)   { return xz.NewWriter(w) }
+func zstdWriter(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }
+
+func zipArchive(t *testing.T, name, content string) []byte {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		name += extIfFound
+	}
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+	for _, file := range []struct{ name, content string }{
+		{"LICENSE", "license"},
+		{name, content},
+	} {
+		writer, err := zipWriter.Create(file.name)
+		require.NoError(t, err)
+		_, err = writer.Write([]byte(file.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	const content = "#!/bin/sh\necho v1.0.0\n"
+	tarball := tarArchive(t, "scanner", content)
+
+	tests := []struct {
+		format asset.Format
+		data   []byte
+	}{
+		{asset.Zip, zipArchive(t, "scanner", content)},
+		{asset.TarGz, compress(t, tarball, gzipWriter)},
+		{asset.TarXz, compress(t, tarball, xzWriter)},
+		{asset.TarZst, compress(t, tarball, zstdWriter)},
+		{asset.Gz, compress(t, []byte(content), gzipWriter)},
+		{asset.Binary, []byte(content)},
+	}
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			path := t.TempDir()
+			require.NoError(t, extract(test.format, bytes.NewReader(test.data), types.Tool{Name: "scanner"}, path))
+			require.Equal(t, content, readBinary(t, path, "scanner"))
+		})
+	}
+}
+
+func TestExtractCorruptArchive(t *testing.T) {
+	for _, format := range []asset.Format{asset.Zip, asset.TarGz, asset.TarXz, asset.TarZst, asset.Gz} {
+		require.Error(t, extract(format, bytes.NewReader([]byte("not an archive")), types.Tool{Name: "scanner"}, t.TempDir()), format)
+	}
+}
+
+// BenchmarkExtractZip extracts a 64 MiB stored archive, the allocations
+// reported per operation stay far below the archive size
+func BenchmarkExtractZip(b *testing.B) {
+	binary := make([]byte, 64<<20)
+	_, _ = rand.Read(binary)
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+	writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "scanner", Method: zip.Store})
+	require.NoError(b, err)
+	_, err = writer.Write(binary)
+	require.NoError(b, err)
+	require.NoError(b, zipWriter.Close())
+	archive := buf.Bytes()
+
+	b.SetBytes(int64(len(archive)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		path := b.TempDir()
+		if err := extract(asset.Zip, bytes.NewReader(archive), types.Tool{Name: "scanner"}, path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package asset selects the release asset of a tool built for a platform
+package asset
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// DefaultTemplate is the asset naming of projectdiscovery releases
+const DefaultTemplate = "{name}_{version}_{os}_{arch}"
+
+// Format is the archive format of a release asset
+type Format string
+
+const (
+	Zip    Format = "zip"
+	TarGz  Format = "tar.gz"
+	TarXz  Format = "tar.xz"
+	TarZst Format = "tar.zst"
+	// Gz is a single gzip compressed binary
+	Gz Format = "gz"
+	// Binary is an uncompressed binary
+	Binary Format = "binary"
+)
+
+// extensions maps asset extensions to their format, longest first so that
+// .tar.gz is not taken for .gz
+var extensions = []struct {
+	ext    string
+	format Format
+}{
+	{".tar.gz", TarGz},
+	{".tgz", TarGz},
+	{".tar.xz", TarXz},
+	{".txz", TarXz},
+	{".tar.zst", TarZst},
+	{".tzst", TarZst},
+	{".zip", Zip},
+	{".gz", Gz},
+	{".exe", Binary},
+}
+
+// ParseFormat returns the format named by value, accepting extensions such as .tgz
+func ParseFormat(value string) (Format, error) {
+	value = strings.ToLower(strings.TrimPrefix(value, "."))
+	if value == string(Binary) {
+		return Binary, nil
+	}
+	for _, extension := range extensions {
+		if value == strings.TrimPrefix(extension.ext, ".") {
+			return extension.format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported archive format %q", value)
+}
+
+// osAliases and archAliases are the spellings used in asset names for each
+// GOOS and GOARCH, e.g. projectdiscovery releases use macOS for darwin
+var (
+	osAliases = map[string][]string{
+		"darwin":  {"darwin", "macOS", "macos", "mac", "osx", "apple"},
+		"linux":   {"linux"},
+		"windows": {"windows", "win", "win64"},
+		"freebsd": {"freebsd"},
+	}
+	archAliases = map[string][]string{
+		"amd64": {"amd64", "x86_64", "x64", "64bit"},
+		"arm64": {"arm64", "aarch64"},
+		"386":   {"386", "i386", "x86", "32bit"},
+		"arm":   {"arm", "armv7", "armv6"},
+	}
+)
+
+// Asset is a release asset matching a platform
+type Asset struct {
+	Name   string
+	ID     string
+	Format Format
+}
+
+// FormatOf returns the archive format of the asset with the given name.
+// Names without an extension, e.g. tool-linux-amd64, are plain binaries
+// while other extensions (.txt, .sig, .deb...) are not installable.
+func FormatOf(name string) (Format, bool) {
+	lower := strings.ToLower(name)
+	for _, extension := range extensions {
+		if strings.HasSuffix(lower, extension.ext) {
+			return extension.format, true
+		}
+	}
+	// the last dot of versioned names such as tool-1.2.3-linux does not start an extension
+	ext := strings.TrimPrefix(filepath.Ext(lower), ".")
+	if ext == "" || strings.ContainsAny(ext, "-_") || strings.Trim(ext, "0123456789") == "" {
+		return Binary, true
+	}
+	return "", false
+}
+
+// formatOf returns the format of the asset name, tools declaring an archive
+// format use it for every asset without a known extension
+func formatOf(tool types.Tool, name string) (Format, bool) {
+	if tool.ArchiveFormat != "" {
+		if format, err := ParseFormat(tool.ArchiveFormat); err == nil {
+			// only assets without a recognized extension take the declared format
+			detected, ok := FormatOf(name)
+			if !ok || (detected != Binary && detected != format) {
+				return "", false
+			}
+			return format, true
+		}
+	}
+	return FormatOf(name)
+}
+
+// Find returns the asset of tool built for the current platform
+func Find(tool types.Tool) (Asset, bool) {
+	return FindFor(tool, runtime.GOOS, runtime.GOARCH)
+}
+
+// FindFor returns the asset of tool built for goos and goarch. Assets are
+// matched against the tool asset template, projectdiscovery tools defaulting
+// to DefaultTemplate. Tools of other owners without a template are matched by
+// the os and arch spelled in the asset name. The os and arch aliases of the
+// tool are tried before the built-in spellings.
+func FindFor(tool types.Tool, goos, goarch string) (Asset, bool) {
+	template := tool.AssetTemplate
+	if template == "" && tool.GetOwner() == types.Organization {
+		template = DefaultTemplate
+	}
+	if template != "" {
+		return matchTemplate(tool, template, goos, goarch)
+	}
+	return matchPlatform(tool, goos, goarch)
+}
+
+// matchTemplate returns the asset named after template expanded for any
+// spelling of goos and goarch, with or without an archive extension
+func matchTemplate(tool types.Tool, template, goos, goarch string) (Asset, bool) {
+	for _, osName := range aliases(tool.OSAliases, osAliases, goos) {
+		for _, archName := range aliases(tool.ArchAliases, archAliases, goarch) {
+			expected := strings.ToLower(Expand(template, tool, osName, archName))
+			for name, id := range tool.Assets {
+				format, ok := formatOf(tool, name)
+				if !ok {
+					continue
+				}
+				lower := strings.ToLower(name)
+				if lower == expected || strings.TrimSuffix(lower, extensionOf(lower)) == expected {
+					return Asset{Name: name, ID: id, Format: format}, true
+				}
+			}
+		}
+	}
+	return Asset{}, false
+}
+
+// extensionOf returns the known extension of the lowercase asset name
+func extensionOf(name string) string {
+	for _, extension := range extensions {
+		if strings.HasSuffix(name, extension.ext) {
+			return extension.ext
+		}
+	}
+	return ""
+}
+
+// Expand replaces the {name}, {version}, {os} and {arch} placeholders of template
+func Expand(template string, tool types.Tool, osName, archName string) string {
+	return strings.NewReplacer(
+		"{name}", tool.Name,
+		"{version}", strings.TrimPrefix(tool.Version, "v"),
+		"{os}", osName,
+		"{arch}", archName,
+	).Replace(template)
+}
+
+// matchPlatform returns the archive whose name spells both goos and goarch.
+// When several match, assets named after the tool are preferred, then the
+// shortest name, which skips variants such as -musl or -static builds.
+func matchPlatform(tool types.Tool, goos, goarch string) (Asset, bool) {
+	var candidates []Asset
+	for name, id := range tool.Assets {
+		format, ok := formatOf(tool, name)
+		if !ok {
+			continue
+		}
+		lower := strings.ToLower(name)
+		tokens := tokenize(strings.TrimSuffix(lower, extensionOf(lower)))
+		if spells(lower, tokens, aliases(tool.OSAliases, osAliases, goos)) && spells(lower, tokens, aliases(tool.ArchAliases, archAliases, goarch)) {
+			candidates = append(candidates, Asset{Name: name, ID: id, Format: format})
+		}
+	}
+	if len(candidates) == 0 {
+		return Asset{}, false
+	}
+	toolName := strings.ToLower(tool.Name)
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i].Name, candidates[j].Name
+		aNamed, bNamed := strings.Contains(strings.ToLower(a), toolName), strings.Contains(strings.ToLower(b), toolName)
+		if aNamed != bNamed {
+			return aNamed
+		}
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	return candidates[0], true
+}
+
+// tokenize splits an asset name on the separators used between its parts.
+// x86_64 is kept as a single token as it contains a separator itself.
+func tokenize(name string) map[string]struct{} {
+	name = strings.NewReplacer("x86_64", "x86~64", "x86-64", "x86~64").Replace(name)
+	tokens := make(map[string]struct{})
+	for _, token := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ' '
+	}) {
+		tokens[strings.ReplaceAll(token, "~", "_")] = struct{}{}
+	}
+	return tokens
+}
+
+// spells reports whether the asset name contains any of values, either as
+// a token or, for values containing separators such as apple-darwin, as is
+func spells(name string, tokens map[string]struct{}, values []string) bool {
+	for _, value := range values {
+		value = strings.ToLower(value)
+		if _, ok := tokens[value]; ok {
+			return true
+		}
+		if strings.ContainsAny(value, "_-. ") && strings.Contains(name, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// aliases returns the spellings of value, those declared by the tool first
+// and value itself when neither table has any
+func aliases(toolTable map[string][]string, table map[string][]string, value string) []string {
+	spellings := append([]string{}, toolTable[value]...)
+	spellings = append(spellings, table[value]...)
+	if len(spellings) == 0 {
+		return []string{value}
+	}
+	return spellings
+}
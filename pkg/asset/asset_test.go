@@ -0,0 +1,152 @@
+package asset
+
+import (
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		tool   types.Tool
+		goos   string
+		goarch string
+		want   Asset
+	}{
+		{
+			name: "projectdiscovery naming",
+			tool: types.Tool{Name: "dnsx", Version: "1.1.1", Assets: map[string]string{
+				"dnsx_1.1.1_linux_amd64.zip":  "1",
+				"dnsx_1.1.1_macOS_arm64.zip":  "2",
+				"dnsx_1.1.1_checksums.txt":    "3",
+				"dnsx_1.1.1_linux_arm64.zip":  "4",
+				"dnsx_1.1.1_windows_386.zip":  "5",
+				"dnsx_1.1.1_macOS_amd64.zip":  "6",
+				"dnsx_1.1.1_linux_386.tar.gz": "7",
+			}},
+			goos: "darwin", goarch: "arm64",
+			want: Asset{Name: "dnsx_1.1.1_macOS_arm64.zip", ID: "2", Format: Zip},
+		},
+		{
+			name: "goreleaser naming",
+			tool: types.Tool{Name: "ffuf", Owner: "ffuf", Version: "2.1.0", Assets: map[string]string{
+				"ffuf_2.1.0_checksums.txt":          "1",
+				"ffuf_2.1.0_linux_amd64.tar.gz":     "2",
+				"ffuf_2.1.0_linux_arm64.tar.gz":     "3",
+				"ffuf_2.1.0_macOS_amd64.tar.gz":     "4",
+				"ffuf_2.1.0_windows_amd64.zip":      "5",
+				"ffuf_2.1.0_linux_amd64.tar.gz.sig": "6",
+			}},
+			goos: "linux", goarch: "amd64",
+			want: Asset{Name: "ffuf_2.1.0_linux_amd64.tar.gz", ID: "2", Format: TarGz},
+		},
+		{
+			name: "unversioned naming with x86_64",
+			tool: types.Tool{Name: "amass", Owner: "owasp-amass", Version: "4.2.0", Assets: map[string]string{
+				"amass_Linux_x86_64.zip":  "1",
+				"amass_Linux_arm64.zip":   "2",
+				"amass_Darwin_x86_64.zip": "3",
+			}},
+			goos: "darwin", goarch: "amd64",
+			want: Asset{Name: "amass_Darwin_x86_64.zip", ID: "3", Format: Zip},
+		},
+		{
+			name: "shortest variant preferred",
+			tool: types.Tool{Name: "gau", Owner: "lc", Version: "2.2.3", Assets: map[string]string{
+				"gau_2.2.3_linux_amd64_musl.tar.gz": "1",
+				"gau_2.2.3_linux_amd64.tar.gz":      "2",
+			}},
+			goos: "linux", goarch: "amd64",
+			want: Asset{Name: "gau_2.2.3_linux_amd64.tar.gz", ID: "2", Format: TarGz},
+		},
+		{
+			name: "asset template",
+			tool: types.Tool{Name: "scanner", Owner: "acme", Version: "v0.3.0", AssetTemplate: "scanner-{os}-{arch}-v{version}", Assets: map[string]string{
+				"scanner-linux-aarch64-v0.3.0.tar.gz":     "1",
+				"scanner-linux-arm64-v0.3.0-debug.tar.gz": "2",
+			}},
+			goos: "linux", goarch: "arm64",
+			want: Asset{Name: "scanner-linux-aarch64-v0.3.0.tar.gz", ID: "1", Format: TarGz},
+		},
+		{
+			name: "tool aliases",
+			tool: types.Tool{Name: "scanner", Owner: "acme", Version: "1.0.0",
+				OSAliases:   map[string][]string{"darwin": {"apple-darwin"}},
+				ArchAliases: map[string][]string{"arm64": {"m1"}},
+				Assets: map[string]string{
+					"scanner-1.0.0-m1-apple-darwin.tar.xz":      "1",
+					"scanner-1.0.0-x86_64-unknown-linux.tar.xz": "2",
+				}},
+			goos: "darwin", goarch: "arm64",
+			want: Asset{Name: "scanner-1.0.0-m1-apple-darwin.tar.xz", ID: "1", Format: TarXz},
+		},
+		{
+			name: "raw binary",
+			tool: types.Tool{Name: "scanner", Owner: "acme", Version: "1.0.0", Assets: map[string]string{
+				"scanner-v1.0.0-linux-amd64":        "1",
+				"scanner-v1.0.0-linux-amd64.sha256": "2",
+				"scanner-v1.0.0-windows-amd64.exe":  "3",
+			}},
+			goos: "linux", goarch: "amd64",
+			want: Asset{Name: "scanner-v1.0.0-linux-amd64", ID: "1", Format: Binary},
+		},
+		{
+			name: "declared archive format",
+			tool: types.Tool{Name: "scanner", Owner: "acme", Version: "1.0.0", AssetTemplate: "{name}-{os}-{arch}", ArchiveFormat: "tgz", Assets: map[string]string{
+				"scanner-linux-amd64":     "1",
+				"scanner-linux-amd64.txt": "2",
+			}},
+			goos: "linux", goarch: "amd64",
+			want: Asset{Name: "scanner-linux-amd64", ID: "1", Format: TarGz},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := FindFor(test.tool, test.goos, test.goarch)
+			require.True(t, ok)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestFindForMissingPlatform(t *testing.T) {
+	tool := types.Tool{Name: "ffuf", Owner: "ffuf", Version: "2.1.0", Assets: map[string]string{
+		"ffuf_2.1.0_linux_amd64.tar.gz": "1",
+		"ffuf_2.1.0_checksums.txt":      "2",
+	}}
+	_, ok := FindFor(tool, "linux", "arm64")
+	require.False(t, ok)
+	_, ok = FindFor(tool, "windows", "amd64")
+	require.False(t, ok)
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]Format{
+		"tool_1.0.0_linux_amd64.zip":     Zip,
+		"tool_1.0.0_linux_amd64.tar.gz":  TarGz,
+		"tool_1.0.0_linux_amd64.tgz":     TarGz,
+		"tool_1.0.0_linux_amd64.tar.xz":  TarXz,
+		"tool_1.0.0_linux_amd64.tar.zst": TarZst,
+		"tool_linux_amd64.gz":            Gz,
+		"tool_windows_amd64.exe":         Binary,
+		"tool_linux_amd64":               Binary,
+		"tool-linux-amd64-v1.2":          Binary,
+	}
+	for name, want := range tests {
+		got, ok := FormatOf(name)
+		require.True(t, ok, name)
+		require.Equal(t, want, got, name)
+	}
+	for _, name := range []string{"tool_1.0.0_checksums.txt", "tool_linux_amd64.tar.gz.sig", "tool_1.0.0_amd64.deb"} {
+		_, ok := FormatOf(name)
+		require.False(t, ok, name)
+	}
+
+	format, err := ParseFormat(".tzst")
+	require.NoError(t, err)
+	require.Equal(t, TarZst, format)
+	_, err = ParseFormat("rar")
+	require.Error(t, err)
+}
@@ -0,0 +1,330 @@
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/asset"
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+const (
+	// bundleToolsFile is the tool list of a bundle, in the format of the
+	// pdtm cache file
+	bundleToolsFile = "tools.json"
+	// bundleAssetsDir holds the release assets of a bundle as assets/<tool>/<asset>
+	bundleAssetsDir = "assets"
+)
+
+// bundledTool is a tool of the bundle tool list with the digests of its
+// bundled assets, which tool lists can not carry
+type bundledTool struct {
+	types.Tool
+	Checksums map[string]string `json:"checksums"`
+}
+
+// Platform is an os/arch combination release assets are bundled for
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// CurrentPlatform returns the platform pdtm is running on
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+// ParsePlatform parses an os/arch pair such as linux/amd64
+func ParsePlatform(value string) (Platform, error) {
+	goos, goarch, ok := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "/")
+	if !ok || goos == "" || goarch == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected os/arch", value)
+	}
+	return Platform{OS: goos, Arch: goarch}, nil
+}
+
+// CreateBundle writes a gzip compressed tarball with the release assets of
+// tools for each platform to writer. The bundled tool list only keeps the
+// assets that were bundled and carries their sha256 digests, so that
+// installs from the bundle are verified without network access. Tools
+// without an asset for any platform are left out.
+func CreateBundle(writer io.Writer, tools []types.Tool, platforms []Platform) error {
+	gzipWriter := gzip.NewWriter(writer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	var bundled []types.Tool
+	for _, tool := range tools {
+		bundledTool, err := bundleTool(tarWriter, tool, platforms)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tool.Name, err)
+		}
+		if len(bundledTool.Assets) == 0 {
+			gologger.Warning().Msgf("%s: no release asset for %s, leaving it out of the bundle", tool.Name, platformList(platforms))
+			continue
+		}
+		bundled = append(bundled, bundledTool)
+	}
+	if len(bundled) == 0 {
+		return errors.New("no release asset to bundle")
+	}
+
+	entries := make([]bundledTool, 0, len(bundled))
+	for _, tool := range bundled {
+		entries = append(entries, bundledTool{Tool: tool, Checksums: tool.Checksums})
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: bundleToolsFile, Mode: 0644, Size: int64(len(data))}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tarWriter.Write(data); err != nil {
+		return err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// bundleTool writes the release assets of tool for platforms to tarWriter
+// and returns tool restricted to them
+func bundleTool(tarWriter *tar.Writer, tool types.Tool, platforms []Platform) (types.Tool, error) {
+	checksums := tool.Checksums
+	if checksums == nil {
+		var err error
+		if checksums, err = FetchChecksums(tool); err != nil {
+			return tool, err
+		}
+		if checksums == nil {
+			gologger.Warning().Msgf("%s: release does not publish %s, bundling without checksum verification", tool.Name, checksumAssetName(tool))
+		}
+	}
+
+	bundled := tool
+	bundled.Assets = make(map[string]string)
+	bundled.Checksums = make(map[string]string)
+	for _, platform := range platforms {
+		releaseAsset, ok := asset.FindFor(tool, platform.OS, platform.Arch)
+		if !ok {
+			gologger.Verbose().Msgf("%s: no release asset for %s", tool.Name, platform)
+			continue
+		}
+		// universal builds serve several platforms with a single asset
+		if _, ok := bundled.Assets[releaseAsset.Name]; ok {
+			continue
+		}
+		var expected string
+		if checksums != nil {
+			if expected, ok = checksums[releaseAsset.Name]; !ok {
+				return tool, fmt.Errorf(types.ErrNoChecksumFound, releaseAsset.Name, checksumAssetName(tool))
+			}
+		}
+		checksum, err := bundleAsset(tarWriter, tool, releaseAsset, expected)
+		if err != nil {
+			return tool, err
+		}
+		bundled.Assets[releaseAsset.Name] = releaseAsset.ID
+		bundled.Checksums[releaseAsset.Name] = checksum
+	}
+	return bundled, nil
+}
+
+// bundleAsset downloads a release asset, verifies it against the expected
+// digest when known and writes it to tarWriter. Tar headers carry the entry
+// size, so the download is spooled to a temporary file first.
+func bundleAsset(tarWriter *tar.Writer, tool types.Tool, releaseAsset asset.Asset, expected string) (_ string, err error) {
+	id, err := strconv.Atoi(releaseAsset.ID)
+	if err != nil {
+		return "", fmt.Errorf("invalid id %q of release asset %s", releaseAsset.ID, releaseAsset.Name)
+	}
+	body, size, err := fetchAsset(tool, id)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			gologger.Warning().Msgf("Error closing response body: %s", err)
+		}
+	}()
+	download := newProgressReader(body, tool.Name, releaseAsset.Name, size)
+	defer func() {
+		download.finish(err)
+	}()
+	reader := newChecksumReader(download)
+
+	spool, err := os.CreateTemp("", "pdtm-bundle-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+	written, err := io.Copy(spool, reader)
+	if err != nil {
+		return "", err
+	}
+	if expected != "" {
+		if err := reader.verify(releaseAsset.Name, expected); err != nil {
+			return "", err
+		}
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	header := &tar.Header{
+		Name: strings.Join([]string{bundleAssetsDir, tool.Name, releaseAsset.Name}, "/"),
+		Mode: 0644,
+		Size: written,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tarWriter, spool); err != nil {
+		return "", err
+	}
+	return reader.sum(), nil
+}
+
+func platformList(platforms []Platform) string {
+	names := make([]string, 0, len(platforms))
+	for _, platform := range platforms {
+		names = append(names, platform.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// Bundle is a bundle unpacked to a temporary directory
+type Bundle struct {
+	dir string
+	// Tools lists the bundled tools with their bundled assets and digests
+	Tools []types.Tool
+}
+
+// OpenBundle unpacks the bundle file, Close removes the unpacked files
+func OpenBundle(file string) (*Bundle, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	dir, err := os.MkdirTemp("", "pdtm-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	bundle := &Bundle{dir: dir}
+	if err := unpackBundle(f, dir); err != nil {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("invalid bundle %s: %w", file, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, bundleToolsFile))
+	if err != nil {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("invalid bundle %s: %w", file, err)
+	}
+	var entries []bundledTool
+	if err := json.Unmarshal(data, &entries); err != nil {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("invalid bundle %s: %w", file, err)
+	}
+	for _, entry := range entries {
+		entry.Tool.Checksums = entry.Checksums
+		bundle.Tools = append(bundle.Tools, entry.Tool)
+	}
+	return bundle, nil
+}
+
+// unpackBundle writes the regular files of a bundle tarball to dir
+func unpackBundle(reader io.Reader, dir string) error {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("entry %q escapes the bundle", header.Name)
+		}
+		if err := writeFile(tarReader, filepath.Join(dir, name), 0644); err != nil {
+			return err
+		}
+	}
+}
+
+// Close removes the unpacked bundle
+func (b *Bundle) Close() error {
+	return os.RemoveAll(b.dir)
+}
+
+// Install installs tool from the bundle at path without network access. An
+// installed older version is replaced, the same or a newer one is kept.
+func (b *Bundle) Install(path string, tool types.Tool) error {
+	_, exists := ospath.GetExecutablePath(path, tool.Name)
+	if exists {
+		if err := checkUpToDate(tool, path); err != nil {
+			return err
+		}
+	}
+	gologger.Info().Msgf("installing %s from bundle...", tool.Name)
+	version, err := installWith(tool, path, b.fetch)
+	if err != nil {
+		return err
+	}
+	gologger.Info().Msgf("installed %s %s from bundle", tool.Name, version)
+	return nil
+}
+
+// fetch opens the bundled release asset of tool with the given id
+func (b *Bundle) fetch(tool types.Tool, id int) (io.ReadCloser, int64, error) {
+	name, ok := assetName(tool, id)
+	if !ok {
+		return nil, 0, fmt.Errorf("asset %d of %s is not in the bundle", id, tool.Name)
+	}
+	assetPath := filepath.Join(bundleAssetsDir, tool.Name, name)
+	if !filepath.IsLocal(assetPath) {
+		return nil, 0, fmt.Errorf("invalid bundled asset %s of %s", name, tool.Name)
+	}
+	f, err := os.Open(filepath.Join(b.dir, assetPath))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s is missing from the bundle: %w", name, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
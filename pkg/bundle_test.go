@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// createBundle writes a bundle of releases for platforms to a temporary file
+func createBundle(t *testing.T, releases []*localRelease, platforms []Platform) string {
+	t.Helper()
+
+	var tools []types.Tool
+	for _, release := range releases {
+		tools = append(tools, release.tool)
+	}
+	// every release is served from memory, assets are looked up by tool
+	fetchAsset = func(tool types.Tool, id int) (io.ReadCloser, int64, error) {
+		for _, release := range releases {
+			if release.tool.Name == tool.Name {
+				return release.fetch(tool, id)
+			}
+		}
+		return nil, 0, errors.New("unknown tool")
+	}
+	t.Cleanup(func() { fetchAsset = downloadAsset })
+
+	file := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(file)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, CreateBundle(f, tools, platforms))
+	return file
+}
+
+// useNoNetwork fails every asset download
+func useNoNetwork(t *testing.T) {
+	t.Helper()
+
+	fetchAsset = func(tool types.Tool, id int) (io.ReadCloser, int64, error) {
+		return nil, 0, errors.New("network access during bundle install")
+	}
+	t.Cleanup(func() { fetchAsset = downloadAsset })
+}
+
+func TestBundleInstall(t *testing.T) {
+	dnsx := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	httpx := newLocalRelease(t, "httpx", "1.3.0", versionScript("1.3.0"))
+	// an asset for a platform nobody asked for stays out of the bundle
+	dnsx.tool.Assets["dnsx_1.1.1_plan9_386.tar.gz"] = "3"
+	file := createBundle(t, []*localRelease{dnsx, httpx}, []Platform{CurrentPlatform(), {OS: "solaris", Arch: "sparc"}})
+
+	useNoNetwork(t)
+	bundle, err := OpenBundle(file)
+	require.NoError(t, err)
+	defer bundle.Close()
+
+	require.Len(t, bundle.Tools, 2)
+	for _, tool := range bundle.Tools {
+		require.Len(t, tool.Assets, 1)
+		require.Len(t, tool.Checksums, 1)
+	}
+
+	pathBin := t.TempDir()
+	for _, tool := range bundle.Tools {
+		require.NoError(t, bundle.Install(pathBin, tool))
+	}
+	require.Equal(t, versionScript("1.1.1"), readBinary(t, pathBin, "dnsx"))
+	require.Equal(t, versionScript("1.3.0"), readBinary(t, pathBin, "httpx"))
+	requireCleanPath(t, pathBin)
+
+	require.NoError(t, bundle.Close())
+	_, err = os.Stat(bundle.dir)
+	require.True(t, os.IsNotExist(err), "unpacked bundle left behind")
+}
+
+func TestBundleUpdate(t *testing.T) {
+	pathBin := t.TempDir()
+	release := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	useLocalRelease(t, release)
+	require.NoError(t, Install(pathBin, release.tool))
+
+	file := createBundle(t, []*localRelease{newLocalRelease(t, "dnsx", "1.1.2", versionScript("1.1.2"))}, []Platform{CurrentPlatform()})
+	useNoNetwork(t)
+	bundle, err := OpenBundle(file)
+	require.NoError(t, err)
+	defer bundle.Close()
+
+	require.NoError(t, bundle.Install(pathBin, bundle.Tools[0]))
+	require.Equal(t, versionScript("1.1.2"), readBinary(t, pathBin, "dnsx"))
+	require.ErrorIs(t, bundle.Install(pathBin, bundle.Tools[0]), types.ErrIsUpToDate)
+}
+
+func TestBundleChecksumMismatch(t *testing.T) {
+	release := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	release.assets[1] = tarGzArchive(t, "dnsx", "tampered")
+	fetchAsset = release.fetch
+	t.Cleanup(func() { fetchAsset = downloadAsset })
+
+	var mismatch *types.ChecksumMismatchError
+	err := CreateBundle(io.Discard, []types.Tool{release.tool}, []Platform{CurrentPlatform()})
+	require.ErrorAs(t, err, &mismatch)
+}
+
+func TestBundleWithoutAssets(t *testing.T) {
+	release := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	useLocalRelease(t, release)
+
+	err := CreateBundle(io.Discard, []types.Tool{release.tool}, []Platform{{OS: "solaris", Arch: "sparc"}})
+	require.Error(t, err)
+}
+
+func TestOpenBundleRejectsEscapingEntries(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "../escaped", Mode: 0644, Size: 1}))
+	_, err := tarWriter.Write([]byte("x"))
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	file := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	require.NoError(t, os.WriteFile(file, buf.Bytes(), 0644))
+	_, err = OpenBundle(file)
+	require.Error(t, err)
+}
+
+func TestParsePlatform(t *testing.T) {
+	platform, err := ParsePlatform("Linux/arm64")
+	require.NoError(t, err)
+	require.Equal(t, Platform{OS: "linux", Arch: "arm64"}, platform)
+
+	for _, value := range []string{"linux", "/amd64", "linux/"} {
+		_, err := ParsePlatform(value)
+		require.Error(t, err, value)
+	}
+}
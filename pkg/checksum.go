@@ -0,0 +1,153 @@
+package pkg
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+var (
+	skipChecksums      bool
+	skipChecksumsMutex sync.RWMutex
+)
+
+// SetSkipChecksums installs release assets without verification when their
+// release does not publish a checksums file
+func SetSkipChecksums(skip bool) {
+	skipChecksumsMutex.Lock()
+	defer skipChecksumsMutex.Unlock()
+	skipChecksums = skip
+}
+
+func currentSkipChecksums() bool {
+	skipChecksumsMutex.RLock()
+	defer skipChecksumsMutex.RUnlock()
+	return skipChecksums
+}
+
+// checksumAssetName returns the name of the checksums file published
+// alongside the release archives, e.g. dnsx_1.1.1_checksums.txt
+func checksumAssetName(tool types.Tool) string {
+	return fmt.Sprintf("%s_%s_checksums.txt", tool.Name, strings.TrimPrefix(tool.Version, "v"))
+}
+
+// FetchChecksums downloads the release checksums file of tool and returns
+// the published sha256 digests by asset name. A nil map is returned when the
+// release does not ship a checksums file.
+func FetchChecksums(tool types.Tool) (map[string]string, error) {
+	assetID, ok := tool.Assets[checksumAssetName(tool)]
+	if !ok {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(assetID)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := fetchAsset(tool, id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			gologger.Warning().Msgf("%s: error closing response body: %s", tool.Name, err)
+		}
+	}()
+	return parseChecksums(body)
+}
+
+// expectedChecksum returns the sha256 digest the given asset must match.
+// Checksums carried by the tool (e.g. from a lockfile) take precedence over
+// the release checksums file. projectdiscovery releases always publish a
+// checksums file, a missing one is an error unless the tool or the user opted
+// out. An empty digest is returned when verification is skipped.
+func expectedChecksum(tool types.Tool, asset string) (string, error) {
+	checksums, source := tool.Checksums, "locked checksums"
+	if checksums == nil {
+		var err error
+		source = checksumAssetName(tool)
+		if checksums, err = FetchChecksums(tool); err != nil {
+			return "", &types.ChecksumUnavailableError{File: source, Err: err}
+		}
+		if checksums == nil {
+			if tool.GetOwner() == types.Organization && !tool.SkipChecksum && !currentSkipChecksums() {
+				return "", &types.ChecksumUnavailableError{File: source}
+			}
+			gologger.Warning().Msgf("%s: release does not publish %s, skipping checksum verification", tool.Name, source)
+			return "", nil
+		}
+	}
+	checksum, ok := checksums[asset]
+	if !ok {
+		return "", fmt.Errorf(types.ErrNoChecksumFound, asset, source)
+	}
+	return checksum, nil
+}
+
+// parseChecksums parses a sha256sum style checksums file into a map of
+// file name to lowercase hex digest
+func parseChecksums(reader io.Reader) (map[string]string, error) {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			continue
+		}
+		// sha256sum prefixes the file name with '*' when run in binary mode
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// checksumReader computes the sha256 digest of everything read through it
+type checksumReader struct {
+	reader io.Reader
+	hash   hash.Hash
+}
+
+func newChecksumReader(reader io.Reader) *checksumReader {
+	h := sha256.New()
+	return &checksumReader{reader: io.TeeReader(reader, h), hash: h}
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// sum returns the hex digest of the data read so far
+func (c *checksumReader) sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+// digest drains any unread data so that the digest covers the whole
+// archive and returns it
+func (c *checksumReader) digest() (string, error) {
+	if _, err := io.Copy(io.Discard, c.reader); err != nil {
+		return "", err
+	}
+	return c.sum(), nil
+}
+
+// verify compares the digest of the whole archive with the expected one
+func (c *checksumReader) verify(asset, expected string) error {
+	actual, err := c.digest()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return &types.ChecksumMismatchError{Asset: asset, Expected: expected, Actual: actual}
+	}
+	return nil
+}
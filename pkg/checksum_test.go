@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChecksums(t *testing.T) {
+	content := `0b7e1c7dbd1a5a1b0ad5bc0e6bbbfc1c76ab8d52cbe1a16a3a4c8a1a0d0c9e8f  dnsx_1.1.1_linux_amd64.zip
+A1B2C3D4E5F60718293A4B5C6D7E8F90A1B2C3D4E5F60718293A4B5C6D7E8F90 *dnsx_1.1.1_macOS_arm64.zip
+
+malformed line
+`
+	checksums, err := parseChecksums(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, checksums, 2)
+	require.Equal(t, "0b7e1c7dbd1a5a1b0ad5bc0e6bbbfc1c76ab8d52cbe1a16a3a4c8a1a0d0c9e8f", checksums["dnsx_1.1.1_linux_amd64.zip"])
+	require.Equal(t, "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90", checksums["dnsx_1.1.1_macOS_arm64.zip"])
+}
+
+func TestChecksumReaderVerify(t *testing.T) {
+	data := "archive content"
+	sum := sha256.Sum256([]byte(data))
+	expected := hex.EncodeToString(sum[:])
+
+	// partially consumed readers are drained before comparing
+	reader := newChecksumReader(strings.NewReader(data))
+	_, err := io.CopyN(io.Discard, reader, 3)
+	require.NoError(t, err)
+	require.NoError(t, reader.verify("asset.zip", expected))
+
+	reader = newChecksumReader(strings.NewReader("tampered content"))
+	err = reader.verify("asset.zip", expected)
+	var mismatchErr *types.ChecksumMismatchError
+	require.True(t, errors.As(err, &mismatchErr))
+	require.Equal(t, "asset.zip", mismatchErr.Asset)
+	require.Equal(t, expected, mismatchErr.Expected)
+}
+
+func TestExpectedChecksumWithoutChecksumsFile(t *testing.T) {
+	t.Cleanup(func() { SetSkipChecksums(false) })
+	tool := types.Tool{Name: "dnsx", Repo: "dnsx", Version: "1.1.1", Assets: map[string]string{"dnsx_1.1.1_linux_amd64.zip": "1"}}
+
+	// projectdiscovery releases always publish a checksums file
+	_, err := expectedChecksum(tool, "dnsx_1.1.1_linux_amd64.zip")
+	require.EqualError(t, err, "release does not publish dnsx_1.1.1_checksums.txt, use -skip-checksum to install it without verification")
+	var unavailableErr *types.ChecksumUnavailableError
+	require.ErrorAs(t, err, &unavailableErr)
+
+	skipped := tool
+	skipped.SkipChecksum = true
+	checksum, err := expectedChecksum(skipped, "dnsx_1.1.1_linux_amd64.zip")
+	require.NoError(t, err)
+	require.Empty(t, checksum)
+
+	thirdParty := tool
+	thirdParty.Owner = "acme"
+	checksum, err = expectedChecksum(thirdParty, "dnsx_1.1.1_linux_amd64.zip")
+	require.NoError(t, err)
+	require.Empty(t, checksum)
+
+	SetSkipChecksums(true)
+	checksum, err = expectedChecksum(tool, "dnsx_1.1.1_linux_amd64.zip")
+	require.NoError(t, err)
+	require.Empty(t, checksum)
+}
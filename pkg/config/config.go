@@ -0,0 +1,187 @@
+// Package config implements the pdtm configuration file, its profiles and
+// the PDTM_* environment variables
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// Environment variables overriding the configuration file and profiles
+const (
+	EnvProfile       = "PDTM_PROFILE"
+	EnvBinaryPath    = "PDTM_BINARY_PATH"
+	EnvConcurrency   = "PDTM_CONCURRENCY"
+	EnvProxy         = "PDTM_PROXY"
+	EnvTools         = "PDTM_TOOLS"
+	EnvDisabledTools = "PDTM_DISABLED_TOOLS"
+)
+
+// Settings are the options set by the configuration file, a profile or the
+// environment. Nil fields are not set.
+type Settings struct {
+	// BinaryPath is where tools are installed
+	BinaryPath *string `yaml:"binary-path"`
+	// Concurrency is the number of tools installed, updated or removed at once
+	Concurrency *int `yaml:"concurrency"`
+	// Proxy is the proxy url for all requests
+	Proxy *string `yaml:"proxy"`
+	// GithubTokens holds GitHub tokens by host
+	GithubTokens map[string]string `yaml:"github-tokens"`
+	// Tools is the default tool set listed and used by -install-all,
+	// -update-all, -remove-all and -check-all, all tools when empty
+	Tools []string `yaml:"tools"`
+	// DisabledTools are never listed, installed or updated
+	DisabledTools []string `yaml:"disabled-tools"`
+}
+
+// Config is the configuration file
+type Config struct {
+	Settings `yaml:",inline"`
+	// Profile is the profile used when none is selected otherwise
+	Profile string `yaml:"profile"`
+	// Profiles are named settings applied over the settings of the file
+	Profiles map[string]Settings `yaml:"profiles"`
+}
+
+// Load reads the configuration file, a missing file is an empty configuration
+func Load(file string) (*Config, error) {
+	config := &Config{}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if empty, err := fileutil.IsEmpty(file); err == nil && empty {
+		return config, nil
+	}
+	// the default file written by goflags only holds comments
+	if err := fileutil.UnmarshalFromReader(fileutil.YAML, f, config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	for name, settings := range config.Profiles {
+		if err := settings.validate(); err != nil {
+			return nil, fmt.Errorf("%s: profile %s: %w", file, name, err)
+		}
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return config, nil
+}
+
+// Resolve returns the settings of the file with those of profile applied
+// over them. An empty profile selects the default profile of the file, if any.
+func (c *Config) Resolve(profile string) (Settings, error) {
+	if profile == "" {
+		profile = c.Profile
+	}
+	if profile == "" {
+		return c.Settings, nil
+	}
+	settings, ok := c.Profiles[profile]
+	if !ok {
+		return Settings{}, fmt.Errorf("unknown profile %q (profiles: %s)", profile, c.profileNames())
+	}
+	return Merge(settings, c.Settings), nil
+}
+
+func (c *Config) profileNames() string {
+	if len(c.Profiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// FromEnv returns the settings of the PDTM_* environment variables read
+// with getenv. Tool lists are comma separated.
+func FromEnv(getenv func(string) string) (Settings, error) {
+	var settings Settings
+	if value := getenv(EnvBinaryPath); value != "" {
+		settings.BinaryPath = &value
+	}
+	if value := getenv(EnvConcurrency); value != "" {
+		concurrency, err := strconv.Atoi(value)
+		if err != nil {
+			return Settings{}, fmt.Errorf("invalid %s %q", EnvConcurrency, value)
+		}
+		settings.Concurrency = &concurrency
+	}
+	if value := getenv(EnvProxy); value != "" {
+		settings.Proxy = &value
+	}
+	if value := getenv(EnvTools); value != "" {
+		settings.Tools = splitList(value)
+	}
+	if value := getenv(EnvDisabledTools); value != "" {
+		settings.DisabledTools = splitList(value)
+	}
+	return settings, settings.validate()
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (s Settings) validate() error {
+	if s.Concurrency != nil && *s.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", *s.Concurrency)
+	}
+	return nil
+}
+
+// Merge merges settings in order of precedence, every field is taken from
+// the first settings that set it. GitHub tokens are merged by host.
+func Merge(settings ...Settings) Settings {
+	var merged Settings
+	for _, s := range settings {
+		if merged.BinaryPath == nil {
+			merged.BinaryPath = s.BinaryPath
+		}
+		if merged.Concurrency == nil {
+			merged.Concurrency = s.Concurrency
+		}
+		if merged.Proxy == nil {
+			merged.Proxy = s.Proxy
+		}
+		if merged.Tools == nil {
+			merged.Tools = s.Tools
+		}
+		if merged.DisabledTools == nil {
+			merged.DisabledTools = s.DisabledTools
+		}
+		for host, token := range s.GithubTokens {
+			if _, ok := merged.GithubTokens[host]; ok {
+				continue
+			}
+			if merged.GithubTokens == nil {
+				merged.GithubTokens = make(map[string]string)
+			}
+			merged.GithubTokens[host] = token
+		}
+	}
+	return merged
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+# flag keys are applied by goflags and ignored here
+silent: true
+binary-path: /opt/pdtm
+concurrency: 4
+github-tokens:
+  github.com: file-token
+  github.example.com: enterprise-token
+disabled-tools:
+  - uncover
+profile: default
+profiles:
+  default:
+    tools: [nuclei, httpx]
+  recon:
+    tools: [subfinder, dnsx, httpx]
+    concurrency: 8
+    github-tokens:
+      github.com: recon-token
+`
+
+func writeConfig(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestLoad(t *testing.T) {
+	config, err := Load(writeConfig(t, testConfig))
+	require.NoError(t, err)
+	require.Equal(t, "/opt/pdtm", *config.BinaryPath)
+	require.Equal(t, 4, *config.Concurrency)
+	require.Nil(t, config.Proxy)
+	require.Equal(t, []string{"uncover"}, config.DisabledTools)
+	require.Equal(t, "default", config.Profile)
+	require.Len(t, config.Profiles, 2)
+
+	config, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, &Config{}, config)
+
+	config, err = Load(writeConfig(t, ""))
+	require.NoError(t, err)
+	require.Equal(t, &Config{}, config)
+
+	config, err = Load(writeConfig(t, "# binary-path: /opt/pdtm\n# concurrency: 4\n"))
+	require.NoError(t, err)
+	require.Equal(t, &Config{}, config)
+
+	_, err = Load(writeConfig(t, "concurrency: 0\n"))
+	require.ErrorContains(t, err, "concurrency must be at least 1")
+	_, err = Load(writeConfig(t, "profiles:\n  recon:\n    concurrency: -1\n"))
+	require.ErrorContains(t, err, "profile recon")
+	_, err = Load(writeConfig(t, "tools: nuclei: httpx\n"))
+	require.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	config, err := Load(writeConfig(t, testConfig))
+	require.NoError(t, err)
+
+	settings, err := config.Resolve("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"nuclei", "httpx"}, settings.Tools, "default profile of the file")
+	require.Equal(t, 4, *settings.Concurrency)
+
+	settings, err = config.Resolve("recon")
+	require.NoError(t, err)
+	require.Equal(t, []string{"subfinder", "dnsx", "httpx"}, settings.Tools)
+	require.Equal(t, 8, *settings.Concurrency, "profile over file")
+	require.Equal(t, "/opt/pdtm", *settings.BinaryPath, "file without profile value")
+	require.Equal(t, []string{"uncover"}, settings.DisabledTools)
+	require.Equal(t, map[string]string{"github.com": "recon-token", "github.example.com": "enterprise-token"}, settings.GithubTokens)
+
+	_, err = config.Resolve("web")
+	require.EqualError(t, err, `unknown profile "web" (profiles: default, recon)`)
+
+	config.Profile = ""
+	settings, err = config.Resolve("")
+	require.NoError(t, err)
+	require.Nil(t, settings.Tools)
+}
+
+func TestMerge(t *testing.T) {
+	path, proxy, one, two := "/cli", "http://127.0.0.1:8080", 1, 2
+
+	merged := Merge(
+		Settings{BinaryPath: &path},
+		Settings{Concurrency: &one, Tools: []string{"nuclei"}, GithubTokens: map[string]string{"github.com": "env"}},
+		Settings{BinaryPath: new(string), Concurrency: &two, Proxy: &proxy, Tools: []string{"httpx"}, DisabledTools: []string{"katana"}, GithubTokens: map[string]string{"github.com": "file", "ghe.local": "file"}},
+	)
+	require.Equal(t, "/cli", *merged.BinaryPath)
+	require.Equal(t, 1, *merged.Concurrency)
+	require.Equal(t, proxy, *merged.Proxy)
+	require.Equal(t, []string{"nuclei"}, merged.Tools)
+	require.Equal(t, []string{"katana"}, merged.DisabledTools)
+	require.Equal(t, map[string]string{"github.com": "env", "ghe.local": "file"}, merged.GithubTokens)
+
+	require.Equal(t, Settings{}, Merge())
+}
+
+func TestFromEnv(t *testing.T) {
+	env := map[string]string{
+		EnvBinaryPath:    "/env",
+		EnvConcurrency:   "6",
+		EnvTools:         "nuclei, httpx,,",
+		EnvDisabledTools: "katana",
+	}
+	settings, err := FromEnv(func(key string) string { return env[key] })
+	require.NoError(t, err)
+	require.Equal(t, "/env", *settings.BinaryPath)
+	require.Equal(t, 6, *settings.Concurrency)
+	require.Nil(t, settings.Proxy)
+	require.Equal(t, []string{"nuclei", "httpx"}, settings.Tools)
+	require.Equal(t, []string{"katana"}, settings.DisabledTools)
+
+	env[EnvConcurrency] = "many"
+	_, err = FromEnv(func(key string) string { return env[key] })
+	require.EqualError(t, err, `invalid PDTM_CONCURRENCY "many"`)
+}
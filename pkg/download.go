@@ -0,0 +1,288 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/httpclient"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// Downloader fetches release assets, retrying transient failures with an
+// exponential backoff and resuming interrupted transfers with Range requests
+type Downloader struct {
+	// Client sends the requests, the shared download client when nil
+	Client *http.Client
+	// MaxRetries is the number of retries after a failed attempt, shared
+	// between the initial request and resuming the transfer
+	MaxRetries int
+	// MinBackoff is the delay before the first retry, doubled on every retry
+	// up to MaxBackoff. A Retry-After response header takes precedence.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Header is added to every request
+	Header http.Header
+}
+
+// NewDownloader returns a Downloader with the default retry policy
+func NewDownloader(client *http.Client) *Downloader {
+	return &Downloader{
+		Client:     client,
+		MaxRetries: 5,
+		MinBackoff: time.Second,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// downloader fetches the release assets of all tools
+var downloader = NewDownloader(nil)
+
+// logPrefixKey is the context key of the prefix of download logs
+type logPrefixKey struct{}
+
+// withToolName returns a context whose downloads are logged with the name
+// of the tool they belong to
+func withToolName(ctx context.Context, toolName string) context.Context {
+	return context.WithValue(ctx, logPrefixKey{}, toolName+": ")
+}
+
+// Download returns the content of url and its size, -1 when unknown. When
+// the connection drops, reading continues from the last byte received. Non
+// 2xx responses are returned as *types.HTTPStatusError, and reading fails
+// with *types.ResumeError when the asset changed since the first response.
+func (d *Downloader) Download(ctx context.Context, url string) (io.ReadCloser, int64, error) {
+	prefix, _ := ctx.Value(logPrefixKey{}).(string)
+	body := &resumableBody{ctx: ctx, downloader: d, url: url, logPrefix: prefix}
+	resp, err := body.open()
+	if err != nil {
+		return nil, 0, err
+	}
+	body.resp = resp
+	body.size = resp.ContentLength
+	body.validator = responseValidator(resp)
+	return body, body.size, nil
+}
+
+// responseValidator returns the ETag of resp, or its Last-Modified date
+// when it has none
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// resumableBody reads a download, reopening it at the current offset when
+// the transfer fails
+type resumableBody struct {
+	ctx        context.Context
+	downloader *Downloader
+	url        string
+	resp       *http.Response
+	size       int64
+	offset     int64
+	attempts   int
+	// logPrefix names the tool of the download in logs
+	logPrefix string
+	// validator is the ETag or Last-Modified of the first response, sent as
+	// If-Range so that the server sends a changed asset in full instead of
+	// the rest of it
+	validator string
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	for {
+		n, err := b.resp.Body.Read(p)
+		b.offset += int64(n)
+		if err == nil || err == io.EOF || !b.canRetry(err) {
+			return n, err
+		}
+		gologger.Verbose().Msgf("%sdownload of %s interrupted at %d bytes, resuming: %s", b.logPrefix, b.url, b.offset, err)
+		_ = b.resp.Body.Close()
+		if err := b.wait(err); err != nil {
+			return n, err
+		}
+		resp, err := b.open()
+		if err != nil {
+			return n, err
+		}
+		b.resp = resp
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (b *resumableBody) Close() error {
+	return b.resp.Body.Close()
+}
+
+func (b *resumableBody) canRetry(err error) bool {
+	return b.attempts < b.downloader.MaxRetries && b.ctx.Err() == nil && !errors.Is(err, context.Canceled)
+}
+
+// open requests the content from the current offset, retrying transient
+// failures. Servers ignoring the Range request send the full content, in
+// which case the bytes already read are skipped unless the asset changed.
+func (b *resumableBody) open() (*http.Response, error) {
+	for {
+		resp, err := b.request()
+		if err == nil {
+			return resp, nil
+		}
+		var statusErr *types.HTTPStatusError
+		if errors.As(err, &statusErr) && !statusErr.Temporary() {
+			return nil, err
+		}
+		var resumeErr *types.ResumeError
+		if errors.As(err, &resumeErr) {
+			return nil, err
+		}
+		if !b.canRetry(err) {
+			return nil, err
+		}
+		if err := b.wait(err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait sleeps before the next attempt, for the delay requested by the
+// server or the backoff of the attempt
+func (b *resumableBody) wait(err error) error {
+	delay := b.downloader.backoff(b.attempts)
+	var retryAfter *retryAfterError
+	if errors.As(err, &retryAfter) {
+		delay = retryAfter.delay
+	}
+	b.attempts++
+	gologger.Verbose().Msgf("%sretrying download of %s in %s (%d/%d): %s", b.logPrefix, b.url, delay, b.attempts, b.downloader.MaxRetries, err)
+	return sleepContext(b.ctx, delay)
+}
+
+func (b *resumableBody) request() (*http.Response, error) {
+	req, err := http.NewRequestWithContext(b.ctx, http.MethodGet, b.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range b.downloader.Header {
+		req.Header[key] = values
+	}
+	if b.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+		if b.validator != "" {
+			req.Header.Set("If-Range", b.validator)
+		}
+	}
+	client := b.downloader.Client
+	if client == nil {
+		client = httpclient.DownloadClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b.offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != b.offset {
+			_ = resp.Body.Close()
+			return nil, &types.ResumeError{URL: b.url, Reason: fmt.Sprintf("requested bytes from %d, got content range %q", b.offset, resp.Header.Get("Content-Range"))}
+		}
+		return resp, nil
+	case resp.StatusCode == http.StatusOK:
+		if b.offset > 0 {
+			// a failed If-Range also yields the full content, of the changed asset
+			if b.validator != "" && responseValidator(resp) != b.validator {
+				_ = resp.Body.Close()
+				return nil, &types.ResumeError{URL: b.url, Reason: "asset changed since the download started"}
+			}
+			if _, err := io.CopyN(io.Discard, resp.Body, b.offset); err != nil {
+				_ = resp.Body.Close()
+				return nil, err
+			}
+		}
+		return resp, nil
+	}
+
+	_ = resp.Body.Close()
+	statusErr := &types.HTTPStatusError{URL: b.url, StatusCode: resp.StatusCode}
+	if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && statusErr.Temporary() {
+		return nil, &retryAfterError{HTTPStatusError: statusErr, delay: delay}
+	}
+	return nil, statusErr
+}
+
+// contentRangeStart returns the first byte of a "bytes <first>-<last>/<size>"
+// Content-Range header
+func contentRangeStart(contentRange string) (int64, bool) {
+	byteRange, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return start, err == nil
+}
+
+// backoff returns the delay before retry number attempt
+func (d *Downloader) backoff(attempt int) time.Duration {
+	delay := d.MinBackoff
+	for i := 0; i < attempt && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.MaxBackoff {
+		delay = d.MaxBackoff
+	}
+	return delay
+}
+
+// retryAfterError is a temporary status error with the delay requested by
+// the server through the Retry-After header
+type retryAfterError struct {
+	*types.HTTPStatusError
+	delay time.Duration
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.HTTPStatusError
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,277 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer serves content, failing the first requests as scripted
+type flakyServer struct {
+	mutex    sync.Mutex
+	content  []byte
+	requests []*http.Request
+	// failures is consumed one entry per request: a status code to respond
+	// with, or dropConnection to send half of the body then drop the connection
+	failures []int
+	// ignoreRange makes the server always respond with the full content
+	ignoreRange bool
+	retryAfter  string
+}
+
+const dropConnection = -1
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	f.requests = append(f.requests, r)
+	failure := 0
+	if len(f.failures) > 0 {
+		failure, f.failures = f.failures[0], f.failures[1:]
+	}
+	f.mutex.Unlock()
+
+	switch {
+	case failure == dropConnection:
+		start := 0
+		if r.Header.Get("Range") != "" && !f.ignoreRange {
+			start, _ = strconv.Atoi(r.Header.Get("Range")[len("bytes=") : len(r.Header.Get("Range"))-1])
+			w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(f.content)-1)+"/"+strconv.Itoa(len(f.content)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(f.content)-start))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(f.content)))
+			w.Header().Set("ETag", `"v1"`)
+		}
+		remaining := f.content[start:]
+		_, _ = w.Write(remaining[:len(remaining)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	case failure != 0:
+		if f.retryAfter != "" {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		w.WriteHeader(failure)
+	case f.ignoreRange:
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(f.content)
+	default:
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "asset.zip", time.Time{}, bytes.NewReader(f.content))
+	}
+}
+
+func (f *flakyServer) rangeHeaders() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var ranges []string
+	for _, r := range f.requests {
+		ranges = append(ranges, r.Header.Get("Range"))
+	}
+	return ranges
+}
+
+func testDownloader(maxRetries int) *Downloader {
+	d := NewDownloader(http.DefaultClient)
+	d.MaxRetries = maxRetries
+	d.MinBackoff, d.MaxBackoff = time.Millisecond, 5*time.Millisecond
+	return d
+}
+
+func testContent() []byte {
+	content := make([]byte, 1<<20)
+	_, _ = rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func download(t *testing.T, d *Downloader, url string) ([]byte, error) {
+	t.Helper()
+
+	body, _, err := d.Download(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	return io.ReadAll(body)
+}
+
+func TestDownloadResumesDroppedConnection(t *testing.T) {
+	flaky := &flakyServer{content: testContent(), failures: []int{dropConnection, dropConnection}}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	data, err := download(t, testDownloader(3), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, flaky.content, data)
+
+	ranges := flaky.rangeHeaders()
+	require.Len(t, ranges, 3)
+	require.Empty(t, ranges[0])
+	require.Equal(t, "bytes=524288-", ranges[1])
+	require.Equal(t, "bytes=786432-", ranges[2])
+}
+
+func TestDownloadRestartsWhenRangeIgnored(t *testing.T) {
+	flaky := &flakyServer{content: testContent(), failures: []int{dropConnection}, ignoreRange: true}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	data, err := download(t, testDownloader(3), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, flaky.content, data)
+}
+
+func TestDownloadRetriesServerErrors(t *testing.T) {
+	flaky := &flakyServer{content: []byte("asset"), failures: []int{http.StatusBadGateway, http.StatusTooManyRequests}, retryAfter: "0"}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	data, err := download(t, testDownloader(3), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "asset", string(data))
+	require.Len(t, flaky.rangeHeaders(), 3)
+}
+
+func TestDownloadStatusErrors(t *testing.T) {
+	flaky := &flakyServer{content: []byte("asset"), failures: []int{http.StatusNotFound}}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	_, err := download(t, testDownloader(3), server.URL)
+	var statusErr *types.HTTPStatusError
+	require.True(t, errors.As(err, &statusErr))
+	require.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	require.Len(t, flaky.rangeHeaders(), 1, "client errors are not retried")
+
+	flaky = &flakyServer{content: []byte("asset"), failures: []int{500, 500, 500}}
+	server = httptest.NewServer(flaky)
+	defer server.Close()
+
+	_, err = download(t, testDownloader(2), server.URL)
+	require.True(t, errors.As(err, &statusErr))
+	require.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+	require.Len(t, flaky.rangeHeaders(), 3)
+}
+
+func TestDownloadGivesUpOnDroppedConnections(t *testing.T) {
+	flaky := &flakyServer{content: testContent(), failures: []int{dropConnection, dropConnection, dropConnection}}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	_, err := download(t, testDownloader(1), server.URL)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	delay, ok := parseRetryAfter("120")
+	require.True(t, ok)
+	require.Equal(t, 2*time.Minute, delay)
+
+	delay, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.True(t, ok)
+	require.InDelta(t, time.Hour.Seconds(), delay.Seconds(), 2)
+
+	_, ok = parseRetryAfter("soon")
+	require.False(t, ok)
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Downloader{MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	require.Equal(t, time.Second, d.backoff(0))
+	require.Equal(t, 4*time.Second, d.backoff(2))
+	require.Equal(t, 10*time.Second, d.backoff(8))
+}
+
+// changingServer drops the first response halfway, then serves a changed
+// asset with another ETag
+type changingServer struct {
+	mutex    sync.Mutex
+	original []byte
+	changed  []byte
+	requests int
+}
+
+func (c *changingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	c.requests++
+	first := c.requests == 1
+	c.mutex.Unlock()
+
+	if first {
+		w.Header().Set("Content-Length", strconv.Itoa(len(c.original)))
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(c.original[:len(c.original)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	// the If-Range validator no longer matches, so the full content is sent
+	w.Header().Set("ETag", `"v2"`)
+	http.ServeContent(w, r, "asset.zip", time.Time{}, bytes.NewReader(c.changed))
+}
+
+func TestDownloadFailsWhenAssetChanges(t *testing.T) {
+	changing := &changingServer{original: testContent(), changed: bytes.Repeat([]byte("changed"), 1<<16)}
+	server := httptest.NewServer(changing)
+	defer server.Close()
+
+	_, err := download(t, testDownloader(3), server.URL)
+	var resumeErr *types.ResumeError
+	require.True(t, errors.As(err, &resumeErr), "got %v", err)
+	require.Equal(t, 2, changing.requests, "a changed asset is not retried")
+}
+
+func TestDownloadRejectsMisplacedContentRange(t *testing.T) {
+	content := testContent()
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		// resumes from the start whatever the requested range
+		w.Header().Set("Content-Range", "bytes 0-"+strconv.Itoa(len(content)-1)+"/"+strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	_, err := download(t, testDownloader(3), server.URL)
+	var resumeErr *types.ResumeError
+	require.True(t, errors.As(err, &resumeErr), "got %v", err)
+}
+
+func TestInstallRestartsChangedDownload(t *testing.T) {
+	pathBin := t.TempDir()
+	release := newLocalRelease(t, "dnsx", "1.1.2", versionScript("1.1.2"))
+	changing := &changingServer{original: tarGzArchive(t, "dnsx", versionScript("1.1.1")), changed: release.assets[1]}
+	server := httptest.NewServer(changing)
+	defer server.Close()
+
+	fetchAsset = func(tool types.Tool, id int) (io.ReadCloser, int64, error) {
+		if id == 1 {
+			return testDownloader(3).Download(context.Background(), server.URL)
+		}
+		return release.fetch(tool, id)
+	}
+	t.Cleanup(func() { fetchAsset = downloadAsset })
+
+	require.NoError(t, Install(pathBin, release.tool))
+	require.Equal(t, versionScript("1.1.2"), readBinary(t, pathBin, "dnsx"))
+	require.Equal(t, 3, changing.requests)
+	requireCleanPath(t, pathBin)
+}
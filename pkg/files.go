@@ -0,0 +1,184 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// stagedFilesDir holds auxiliary files within the staging directory
+const stagedFilesDir = ".files"
+
+// userConfigDir returns the directory default configs are installed to,
+// overridden in tests
+var userConfigDir = func() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config"), nil
+}
+
+// matchFile returns the index of the auxiliary file of tool matching the
+// archive entry, either by its path in the archive or by its base name
+func matchFile(tool types.Tool, entryName string) (int, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(entryName)), "/")
+	for i, file := range tool.Files {
+		if ok, _ := path.Match(file.Pattern, name); ok {
+			return i, true
+		}
+		if ok, _ := path.Match(file.Pattern, path.Base(name)); ok {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// stagedFilePath returns where the archive entry matching the auxiliary file
+// with index i is staged
+func stagedFilePath(stagingDir string, i int, entryName string) string {
+	return filepath.Join(stagingDir, stagedFilesDir, strconv.Itoa(i), path.Base(filepath.ToSlash(entryName)))
+}
+
+// fileDestination returns where an auxiliary file named name is installed.
+// Shared files go to the share directory next to the binary path, e.g.
+// ~/.pdtm/go/share for ~/.pdtm/go/bin, and configs to ~/.config/<tool>.
+// Names come from the tool list, so they must be plain file names that
+// stay in their directory.
+func fileDestination(binPath string, tool types.Tool, file types.ToolFile, name string) (string, error) {
+	if file.Name != "" {
+		name = file.Name
+	}
+	if !isFileName(name) {
+		return "", fmt.Errorf("invalid file name %q for %s", name, file.Pattern)
+	}
+	share := filepath.Join(filepath.Dir(filepath.Clean(binPath)), "share")
+	var dir string
+	switch file.Kind {
+	case types.BashCompletion:
+		dir = filepath.Join(share, "bash-completion", "completions")
+	case types.ZshCompletion:
+		dir = filepath.Join(share, "zsh", "site-functions")
+	case types.FishCompletion:
+		dir = filepath.Join(share, "fish", "vendor_completions.d")
+	case types.ManPage:
+		section := "1"
+		if ext := strings.TrimPrefix(filepath.Ext(name), "."); ext != "" && ext[0] >= '1' && ext[0] <= '9' {
+			section = ext[:1]
+		}
+		dir = filepath.Join(share, "man", "man"+section)
+	case types.Config, types.Doc:
+		if !isFileName(tool.Name) {
+			return "", fmt.Errorf("invalid tool name %q for %s", tool.Name, file.Pattern)
+		}
+		if file.Kind == types.Doc {
+			dir = filepath.Join(share, "doc", tool.Name)
+			break
+		}
+		configDir, err := userConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(configDir, tool.Name)
+	default:
+		return "", fmt.Errorf("unknown file kind %q for %s", file.Kind, file.Pattern)
+	}
+	destination := filepath.Join(dir, name)
+	if rel, err := filepath.Rel(dir, destination); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("file %q for %s escapes %s", name, file.Pattern, dir)
+	}
+	return destination, nil
+}
+
+// isFileName reports whether name is a single local path element
+func isFileName(name string) bool {
+	return filepath.IsLocal(name) && !strings.ContainsAny(name, `/\`)
+}
+
+// installFiles moves the staged auxiliary files of tool to their destination
+// and returns them to be recorded in the receipt, so that Remove can clean
+// them up. Files in the receipt of a previous install that are no longer
+// shipped are removed. Existing configs are never overwritten.
+func installFiles(stagingDir, binPath string, tool types.Tool) ([]string, error) {
+	previous := recordedFiles(binPath, tool.Name)
+	stale := make(map[string]struct{}, len(previous))
+	for _, file := range previous {
+		stale[file] = struct{}{}
+	}
+
+	var installed []string
+	for i, file := range tool.Files {
+		entries, err := os.ReadDir(filepath.Join(stagingDir, stagedFilesDir, strconv.Itoa(i)))
+		if os.IsNotExist(err) {
+			gologger.Warning().Msgf("%s: no file matching %s in release archive", tool.Name, file.Pattern)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			destination, err := fileDestination(binPath, tool, file, entry.Name())
+			if err != nil {
+				return nil, err
+			}
+			_, ours := stale[destination]
+			delete(stale, destination)
+			if file.Kind == types.Config && fileutil.FileExists(destination) {
+				// configs may have been edited, those created by pdtm stay recorded
+				if ours {
+					installed = append(installed, destination)
+				} else {
+					gologger.Verbose().Msgf("%s: keeping existing config %s", tool.Name, destination)
+				}
+				continue
+			}
+			if err := moveFile(filepath.Join(stagingDir, stagedFilesDir, strconv.Itoa(i), entry.Name()), destination); err != nil {
+				return nil, err
+			}
+			installed = append(installed, destination)
+		}
+	}
+
+	for file := range stale {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			gologger.Warning().Msgf("%s: error removing %s: %s", tool.Name, file, err)
+		}
+	}
+	return installed, nil
+}
+
+// removeFiles removes the auxiliary files installed for toolName
+func removeFiles(toolName string, files []string) {
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			gologger.Warning().Msgf("%s: error removing %s: %s", toolName, file, err)
+		}
+	}
+}
+
+// moveFile moves src to dst, copying it when they are on different filesystems
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := srcFile.Close(); err != nil {
+			gologger.Warning().Msgf("Error closing %s: %s", src, err)
+		}
+	}()
+	return writeFile(srcFile, dst, 0644)
+}
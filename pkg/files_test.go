@@ -0,0 +1,209 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// releaseWithFiles returns a release whose archive holds the given files
+// next to the binary
+func releaseWithFiles(t *testing.T, version string, files map[string]string) *localRelease {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	binary := "subfinder"
+	if runtime.GOOS == "windows" {
+		binary += extIfFound
+	}
+	files[binary] = versionScript(version)
+	for name, content := range files {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+		_, err := tarWriter.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	archiveName := fmt.Sprintf("subfinder_%s_%s.tar.gz", version, ospath.CheckOS())
+	sum := sha256.Sum256(buf.Bytes())
+	checksums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archiveName)
+
+	return &localRelease{
+		tool: types.Tool{
+			Name:    "subfinder",
+			Repo:    "subfinder",
+			Version: version,
+			Assets: map[string]string{
+				archiveName: "1",
+				fmt.Sprintf("subfinder_%s_checksums.txt", version): "2",
+			},
+			Files: []types.ToolFile{
+				{Pattern: "completions/subfinder.bash", Kind: types.BashCompletion, Name: "subfinder"},
+				{Pattern: "completions/_subfinder", Kind: types.ZshCompletion},
+				{Pattern: "*.1", Kind: types.ManPage},
+				{Pattern: "config.yaml", Kind: types.Config},
+			},
+		},
+		assets: map[int][]byte{1: buf.Bytes(), 2: []byte(checksums)},
+	}
+}
+
+func useConfigDir(t *testing.T, configDir string) {
+	t.Helper()
+
+	original := userConfigDir
+	userConfigDir = func() (string, error) { return configDir, nil }
+	t.Cleanup(func() { userConfigDir = original })
+}
+
+func TestInstallFiles(t *testing.T) {
+	store := useReceipts(t)
+	root := t.TempDir()
+	pathBin := filepath.Join(root, "bin")
+	configDir := filepath.Join(root, "config")
+	useConfigDir(t, configDir)
+
+	bash := filepath.Join(root, "share", "bash-completion", "completions", "subfinder")
+	zsh := filepath.Join(root, "share", "zsh", "site-functions", "_subfinder")
+	man := filepath.Join(root, "share", "man", "man1", "subfinder.1")
+	config := filepath.Join(configDir, "subfinder", "config.yaml")
+
+	release := releaseWithFiles(t, "2.6.0", map[string]string{
+		"completions/subfinder.bash": "complete -F _subfinder subfinder",
+		"completions/_subfinder":     "#compdef subfinder",
+		"docs/subfinder.1":           ".TH SUBFINDER 1",
+		"config.yaml":                "threads: 10",
+		"README.md":                  "not installed",
+	})
+	useLocalRelease(t, release)
+	require.NoError(t, Install(pathBin, release.tool))
+	require.Equal(t, versionScript("2.6.0"), readBinary(t, pathBin, "subfinder"))
+	for file, content := range map[string]string{
+		bash:   "complete -F _subfinder subfinder",
+		zsh:    "#compdef subfinder",
+		man:    ".TH SUBFINDER 1",
+		config: "threads: 10",
+	} {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err, file)
+		require.Equal(t, content, string(data))
+	}
+
+	// the update drops the zsh completion and must keep the edited config
+	require.NoError(t, os.WriteFile(config, []byte("threads: 50"), 0644))
+	release = releaseWithFiles(t, "2.6.1", map[string]string{
+		"completions/subfinder.bash": "complete -F _subfinder subfinder # 2.6.1",
+		"docs/subfinder.1":           ".TH SUBFINDER 1",
+		"config.yaml":                "threads: 10",
+	})
+	useLocalRelease(t, release)
+	require.NoError(t, Update(pathBin, release.tool, true))
+	data, err := os.ReadFile(bash)
+	require.NoError(t, err)
+	require.Equal(t, "complete -F _subfinder subfinder # 2.6.1", string(data))
+	require.NoFileExists(t, zsh)
+	data, err = os.ReadFile(config)
+	require.NoError(t, err)
+	require.Equal(t, "threads: 50", string(data))
+
+	r, ok := store.Get(pathBin, "subfinder")
+	require.True(t, ok)
+	require.ElementsMatch(t, []string{bash, man, config}, r.Files)
+
+	require.NoError(t, Remove(pathBin, release.tool))
+	for _, file := range []string{bash, man, config} {
+		require.NoFileExists(t, file)
+	}
+	requireCleanPath(t, pathBin)
+}
+
+func TestInstallFilesKeepsExistingConfig(t *testing.T) {
+	useReceipts(t)
+	root := t.TempDir()
+	pathBin := filepath.Join(root, "bin")
+	configDir := filepath.Join(root, "config")
+	useConfigDir(t, configDir)
+
+	config := filepath.Join(configDir, "subfinder", "config.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(config), os.ModePerm))
+	require.NoError(t, os.WriteFile(config, []byte("threads: 50"), 0644))
+
+	release := releaseWithFiles(t, "2.6.0", map[string]string{"config.yaml": "threads: 10"})
+	useLocalRelease(t, release)
+	require.NoError(t, Install(pathBin, release.tool))
+	require.NoError(t, Remove(pathBin, release.tool))
+
+	data, err := os.ReadFile(config)
+	require.NoError(t, err)
+	require.Equal(t, "threads: 50", string(data), "configs not created by pdtm are never removed")
+}
+
+func TestInstallFilesRejectsTraversal(t *testing.T) {
+	store := useReceipts(t)
+	root := t.TempDir()
+	pathBin := filepath.Join(root, "home", "bin")
+	configDir := filepath.Join(root, "home", "config")
+	useConfigDir(t, configDir)
+
+	release := releaseWithFiles(t, "2.6.0", map[string]string{"config.yaml": "alias ls=evil"})
+	release.tool.Files = []types.ToolFile{{Pattern: "config.yaml", Kind: types.Config, Name: "../../.bashrc"}}
+	useLocalRelease(t, release)
+	require.NoError(t, Install(pathBin, release.tool))
+	require.NoFileExists(t, filepath.Join(root, "home", ".bashrc"))
+	r, ok := store.Get(pathBin, "subfinder")
+	require.True(t, ok)
+	require.Empty(t, r.Files)
+
+	for _, test := range []struct {
+		tool types.Tool
+		file types.ToolFile
+	}{
+		{types.Tool{Name: "subfinder"}, types.ToolFile{Kind: types.ManPage, Name: "../../../.bashrc"}},
+		{types.Tool{Name: "subfinder"}, types.ToolFile{Kind: types.BashCompletion, Name: "sub/finder"}},
+		{types.Tool{Name: "subfinder"}, types.ToolFile{Kind: types.ZshCompletion, Name: ".."}},
+		{types.Tool{Name: "../.."}, types.ToolFile{Kind: types.Doc}},
+		{types.Tool{Name: "../.ssh"}, types.ToolFile{Kind: types.Config}},
+	} {
+		_, err := fileDestination(pathBin, test.tool, test.file, "authorized_keys")
+		require.Error(t, err, "%s %s", test.tool.Name, test.file.Name)
+	}
+}
+
+func TestInstallFilesBroadPattern(t *testing.T) {
+	useReceipts(t)
+	root := t.TempDir()
+	pathBin := filepath.Join(root, "bin")
+	useConfigDir(t, filepath.Join(root, "config"))
+
+	release := releaseWithFiles(t, "2.6.0", map[string]string{
+		"completions/subfinder": "complete -F _subfinder subfinder",
+		"README.md":             "# subfinder",
+	})
+	release.tool.Files = []types.ToolFile{
+		{Pattern: "completions/*", Kind: types.BashCompletion},
+		{Pattern: "*", Kind: types.Doc},
+	}
+	useLocalRelease(t, release)
+	require.NoError(t, Install(pathBin, release.tool))
+	require.Equal(t, versionScript("2.6.0"), readBinary(t, pathBin, "subfinder"))
+
+	share := filepath.Join(root, "share")
+	data, err := os.ReadFile(filepath.Join(share, "bash-completion", "completions", "subfinder"))
+	require.NoError(t, err)
+	require.Equal(t, "complete -F _subfinder subfinder", string(data))
+	require.FileExists(t, filepath.Join(share, "doc", "subfinder", "README.md"))
+	require.NoFileExists(t, filepath.Join(share, "doc", "subfinder", "subfinder"))
+}
@@ -2,18 +2,250 @@ package pkg
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/google/go-github/github"
+	"github.com/projectdiscovery/pdtm/pkg/httpclient"
+	fileutil "github.com/projectdiscovery/utils/file"
 	"golang.org/x/oauth2"
 )
 
+const (
+	// githubHost is the host of github.com tokens
+	githubHost = "github.com"
+	// defaultAPIHost serves the api of github.com
+	defaultAPIHost = "api.github.com"
+)
+
+// GithubOptions configures the GitHub instance the api clients talk to
+type GithubOptions struct {
+	// APIURL is the api base url, https://api.github.com/ when empty. A
+	// GitHub Enterprise url without a path gets the /api/v3/ prefix.
+	APIURL string
+	// UploadURL is the uploads url, derived from APIURL when empty
+	UploadURL string
+	// DownloadURL serves public release downloads as
+	// <url>/<owner>/<repo>/releases/download/<tag>/<asset>, derived from
+	// APIURL when empty
+	DownloadURL string
+	// Tokens holds tokens by host, they take precedence over the
+	// environment and the gh cli
+	Tokens map[string]string
+	// FallbackTokens holds tokens by host from the configuration file, they
+	// are used after the environment and before the gh cli
+	FallbackTokens map[string]string
+}
+
+// githubConfig is the parsed GithubOptions
+type githubConfig struct {
+	apiURL      *url.URL
+	uploadURL   *url.URL
+	downloadURL string
+	// host selects the token sent with api requests
+	host           string
+	tokens         map[string]string
+	fallbackTokens map[string]string
+}
+
+var (
+	githubMutex sync.RWMutex
+	// the default options contain no url that could fail to parse
+	currentGithub, _ = newGithubConfig(GithubOptions{})
+)
+
+// ConfigureGithub sets the GitHub instance used by GithubClient and release downloads
+func ConfigureGithub(options GithubOptions) error {
+	config, err := newGithubConfig(options)
+	if err != nil {
+		return err
+	}
+	githubMutex.Lock()
+	defer githubMutex.Unlock()
+	currentGithub = config
+	return nil
+}
+
+func githubSettings() githubConfig {
+	githubMutex.RLock()
+	defer githubMutex.RUnlock()
+	return currentGithub
+}
+
+func newGithubConfig(options GithubOptions) (githubConfig, error) {
+	apiURL, err := parseGithubURL(options.APIURL, "https://"+defaultAPIHost+"/")
+	if err != nil {
+		return githubConfig{}, err
+	}
+	enterprise := apiURL.Host != defaultAPIHost
+	if enterprise && strings.Trim(apiURL.Path, "/") == "" {
+		apiURL.Path = "/api/v3/"
+	}
+
+	defaultUpload := "https://uploads.github.com/"
+	if enterprise {
+		defaultUpload = apiURL.Scheme + "://" + apiURL.Host + "/api/uploads/"
+	}
+	uploadURL, err := parseGithubURL(options.UploadURL, defaultUpload)
+	if err != nil {
+		return githubConfig{}, err
+	}
+
+	host := githubHost
+	if enterprise {
+		host = apiURL.Hostname()
+	}
+	downloadURL := options.DownloadURL
+	if downloadURL == "" {
+		downloadURL = "https://" + githubHost
+		if enterprise {
+			downloadURL = apiURL.Scheme + "://" + apiURL.Host
+		}
+	} else if _, err := parseGithubURL(downloadURL, ""); err != nil {
+		return githubConfig{}, err
+	}
+
+	return githubConfig{
+		apiURL:         apiURL,
+		uploadURL:      uploadURL,
+		downloadURL:    strings.TrimSuffix(downloadURL, "/"),
+		host:           host,
+		tokens:         options.Tokens,
+		fallbackTokens: options.FallbackTokens,
+	}, nil
+}
+
+// GithubHost returns the host whose token authenticates requests to the
+// api at apiURL, github.com for the default or an invalid url
+func GithubHost(apiURL string) string {
+	config, err := newGithubConfig(GithubOptions{APIURL: apiURL})
+	if err != nil {
+		return githubHost
+	}
+	return config.host
+}
+
+// parseGithubURL parses an absolute url with a trailing slash, as required
+// by go-github, falling back to defaultValue when value is empty
+func parseGithubURL(value, defaultValue string) (*url.URL, error) {
+	if value == "" {
+		value = defaultValue
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid GitHub url %q", value)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+// GithubClient returns a GitHub api client for the configured instance on
+// top of the shared http client. Requests are authenticated with the token
+// of its host and wait for short rate limit resets.
 func GithubClient() *github.Client {
-	var httpclient *http.Client
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		httpclient = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	config := githubSettings()
+	shared := httpclient.Client()
+	// a new client is built every call, go-github changes its CheckRedirect
+	client := &http.Client{
+		Transport: &rateLimitTransport{next: shared.Transport, rateLimits: githubRateLimits, timeout: shared.Timeout},
 	}
-	githubClient := github.NewClient(httpclient)
+	if token := githubToken(config.host); token != "" {
+		// the token is added on top of the rate limited transport
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+		client = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	}
+	githubClient := github.NewClient(client)
+	githubClient.BaseURL, githubClient.UploadURL = config.apiURL, config.uploadURL
 	return githubClient
 }
+
+// GithubAuthenticated reports whether requests to the configured GitHub api
+// are sent with a token
+func GithubAuthenticated() bool {
+	return githubToken(githubSettings().host) != ""
+}
+
+// githubToken returns the token for host. Configured tokens come first,
+// then GITHUB_TOKEN or GH_TOKEN for github.com and GH_ENTERPRISE_TOKEN or
+// GITHUB_ENTERPRISE_TOKEN for other hosts, then the tokens of the
+// configuration file and the hosts file of the gh cli.
+func githubToken(host string) string {
+	config := githubSettings()
+	if token := config.tokens[host]; token != "" {
+		return token
+	}
+	keys := []string{"GITHUB_TOKEN", "GH_TOKEN"}
+	if host != githubHost {
+		keys = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, key := range keys {
+		if token := os.Getenv(key); token != "" {
+			return token
+		}
+	}
+	if token := config.fallbackTokens[host]; token != "" {
+		return token
+	}
+	return ghCLIToken(host)
+}
+
+// ghHost is a host entry of the gh cli hosts file
+type ghHost struct {
+	OAuthToken string `yaml:"oauth_token"`
+	User       string `yaml:"user"`
+	Users      map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	} `yaml:"users"`
+}
+
+// ghCLIToken returns the token gh auth login stored for host. Tokens kept in
+// the system keyring by newer gh versions are not read.
+func ghCLIToken(host string) string {
+	f, err := os.Open(filepath.Join(ghConfigDir(), "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var hosts map[string]ghHost
+	if err := fileutil.UnmarshalFromReader(fileutil.YAML, f, &hosts); err != nil {
+		return ""
+	}
+	entry, ok := hosts[host]
+	if !ok {
+		return ""
+	}
+	if entry.OAuthToken != "" {
+		return entry.OAuthToken
+	}
+	return entry.Users[entry.User].OAuthToken
+}
+
+// ghConfigDir returns the configuration directory of the gh cli
+func ghConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI")
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh")
+}
@@ -0,0 +1,153 @@
+package pkg
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// useGithub configures the GitHub instance for the duration of the test
+func useGithub(t *testing.T, options GithubOptions) {
+	t.Helper()
+
+	require.NoError(t, ConfigureGithub(options))
+	t.Cleanup(func() {
+		require.NoError(t, ConfigureGithub(GithubOptions{}))
+	})
+}
+
+func TestGithubOptions(t *testing.T) {
+	config, err := newGithubConfig(GithubOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "https://api.github.com/", config.apiURL.String())
+	require.Equal(t, "https://uploads.github.com/", config.uploadURL.String())
+	require.Equal(t, "https://github.com", config.downloadURL)
+	require.Equal(t, githubHost, config.host)
+
+	config, err = newGithubConfig(GithubOptions{APIURL: "https://ghe.corp.example"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ghe.corp.example/api/v3/", config.apiURL.String())
+	require.Equal(t, "https://ghe.corp.example/api/uploads/", config.uploadURL.String())
+	require.Equal(t, "https://ghe.corp.example", config.downloadURL)
+	require.Equal(t, "ghe.corp.example", config.host)
+
+	config, err = newGithubConfig(GithubOptions{
+		APIURL:      "https://ghe.corp.example/custom/api",
+		UploadURL:   "https://uploads.corp.example",
+		DownloadURL: "https://downloads.corp.example/",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://ghe.corp.example/custom/api/", config.apiURL.String())
+	require.Equal(t, "https://uploads.corp.example/", config.uploadURL.String())
+	require.Equal(t, "https://downloads.corp.example", config.downloadURL)
+
+	for _, options := range []GithubOptions{{APIURL: "ghe.corp.example"}, {UploadURL: "/uploads"}, {DownloadURL: "::"}} {
+		require.Error(t, ConfigureGithub(options))
+	}
+}
+
+func TestGithubEnterprise(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "github_token")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise_token")
+
+	var authorizations []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v3/repos/acme/scanner/releases/tags/v1.0.0":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"tag_name": "v1.0.0",
+				"body":     "release notes",
+				"assets":   []map[string]any{{"id": 7, "name": "scanner_linux_amd64.tar.gz"}},
+			})
+		case "/api/v3/repos/acme/scanner/releases/assets/7":
+			http.Redirect(w, r, server.URL+"/storage/scanner_linux_amd64.tar.gz", http.StatusFound)
+		case "/storage/scanner_linux_amd64.tar.gz":
+			_, _ = io.WriteString(w, "archive")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	useGithub(t, GithubOptions{APIURL: server.URL})
+
+	tool, err := GetRelease(types.Tool{Name: "scanner", Owner: "acme", Repo: "scanner"}, "1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0", tool.Version)
+	require.Equal(t, "7", tool.Assets["scanner_linux_amd64.tar.gz"])
+
+	body, err := fetchReleaseBody("acme", "scanner", "1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "release notes", body)
+
+	rc, _, err := downloadAsset(tool, 7)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	require.Equal(t, "archive", string(data))
+
+	// the api gets the enterprise token, the github.com token never leaves
+	require.Equal(t, []string{"Bearer enterprise_token", "Bearer enterprise_token", "Bearer enterprise_token", ""}, authorizations)
+}
+
+func TestGithubToken(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("GH_CONFIG_DIR", configDir)
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "")
+	t.Setenv("GITHUB_ENTERPRISE_TOKEN", "")
+	require.Empty(t, githubToken(githubHost))
+
+	hosts := filepath.Join(configDir, "hosts.yml")
+	require.NoError(t, os.WriteFile(hosts, []byte(`github.com:
+    user: octocat
+    oauth_token: gho_legacy
+    git_protocol: https
+ghe.corp.example:
+    user: octocat
+    oauth_token: gho_enterprise
+`), 0600))
+	require.Equal(t, "gho_legacy", githubToken(githubHost))
+	require.Equal(t, "gho_enterprise", githubToken("ghe.corp.example"))
+	require.Empty(t, githubToken("github.example.com"))
+
+	// multi account hosts files keep a token per user
+	require.NoError(t, os.WriteFile(hosts, []byte(`github.com:
+    user: octocat
+    users:
+        octocat:
+            oauth_token: gho_account
+`), 0600))
+	require.Equal(t, "gho_account", githubToken(githubHost))
+
+	t.Setenv("GH_TOKEN", "gh_env")
+	require.Equal(t, "gh_env", githubToken(githubHost))
+	t.Setenv("GITHUB_TOKEN", "github_env")
+	require.Equal(t, "github_env", githubToken(githubHost))
+
+	// environment tokens are bound to their host
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise_env")
+	require.Equal(t, "enterprise_env", githubToken("ghe.corp.example"))
+	require.Equal(t, "github_env", githubToken(githubHost))
+
+	useGithub(t, GithubOptions{Tokens: map[string]string{"ghe.corp.example": "configured"}})
+	require.Equal(t, "configured", githubToken("ghe.corp.example"))
+	require.Equal(t, "github_env", githubToken(githubHost))
+
+	// tokens of the configuration file come after the environment
+	useGithub(t, GithubOptions{FallbackTokens: map[string]string{githubHost: "file", "ghe.corp.example": "file"}})
+	require.Equal(t, "github_env", githubToken(githubHost))
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	require.Equal(t, "file", githubToken(githubHost))
+}
@@ -0,0 +1,150 @@
+// Package httpclient provides the HTTP clients shared by every outbound
+// request of pdtm: the pdtm api, the GitHub api and release downloads
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent is sent when Options.UserAgent is empty
+const DefaultUserAgent = "pdtm"
+
+// Options configures the shared clients
+type Options struct {
+	// Proxy is the url of an http, https or socks5 proxy. The HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables apply when empty.
+	Proxy string
+	// CAFile is a PEM bundle of certificates trusted in addition to the
+	// system roots, e.g. the CA of an intercepting proxy
+	CAFile string
+	// Insecure disables TLS certificate verification
+	Insecure bool
+	// Timeout bounds api requests. Downloads are only bounded by
+	// ConnectTimeout, large assets taking longer than any request timeout.
+	Timeout time.Duration
+	// ConnectTimeout bounds establishing connections, TLS handshakes and
+	// waiting for response headers
+	ConnectTimeout time.Duration
+	UserAgent      string
+}
+
+// DefaultOptions returns the options used until Configure is called
+func DefaultOptions() Options {
+	return Options{
+		Timeout:        30 * time.Second,
+		ConnectTimeout: 10 * time.Second,
+		UserAgent:      DefaultUserAgent,
+	}
+}
+
+var (
+	mutex          sync.RWMutex
+	client         *http.Client
+	downloadClient *http.Client
+)
+
+func init() {
+	// the default options contain no file or url that could fail to load
+	_ = Configure(DefaultOptions())
+}
+
+// Configure replaces the shared clients with clients built from options
+func Configure(options Options) error {
+	transport, err := newTransport(options)
+	if err != nil {
+		return err
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	client = &http.Client{Transport: transport, Timeout: options.Timeout}
+	downloadClient = &http.Client{Transport: transport}
+	return nil
+}
+
+// Client returns the client for api requests
+func Client() *http.Client {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return client
+}
+
+// DownloadClient returns the client for release downloads, which has no
+// overall timeout
+func DownloadClient() *http.Client {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return downloadClient
+}
+
+func newTransport(options Options) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.Proxy = http.ProxyFromEnvironment
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %q", options.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.Insecure}
+	if options.CAFile != "" {
+		pool, err := certPool(options.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if options.ConnectTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: options.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = options.ConnectTimeout
+		transport.ResponseHeaderTimeout = options.ConnectTimeout
+	}
+
+	userAgent := options.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return &userAgentTransport{next: transport, userAgent: userAgent}, nil
+}
+
+// certPool returns the system roots extended with the certificates of caFile
+func certPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ca file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in ca file %s", caFile)
+	}
+	return pool, nil
+}
+
+// userAgentTransport sets the User-Agent of every request, replacing the
+// defaults of libraries such as go-github
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// round trippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// configure applies options for the duration of the test
+func configure(t *testing.T, options Options) {
+	t.Helper()
+	require.Nil(t, Configure(options))
+	t.Cleanup(func() {
+		require.Nil(t, Configure(DefaultOptions()))
+	})
+}
+
+func TestCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	_, err := Client().Get(server.URL)
+	require.NotNil(t, err, "self-signed certificate must not be trusted by default")
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	require.Nil(t, os.WriteFile(caFile, pem.EncodeToMemory(block), 0644))
+
+	options := DefaultOptions()
+	options.CAFile = caFile
+	configure(t, options)
+
+	for _, client := range []*http.Client{Client(), DownloadClient()} {
+		resp, err := client.Get(server.URL)
+		require.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.Equal(t, "ok", string(body))
+	}
+}
+
+func TestInvalidCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.Nil(t, os.WriteFile(caFile, []byte("not a certificate"), 0644))
+
+	options := DefaultOptions()
+	options.CAFile = caFile
+	require.NotNil(t, Configure(options))
+
+	options.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+	require.NotNil(t, Configure(options))
+}
+
+func TestInsecure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.Insecure = true
+	configure(t, options)
+
+	resp, err := Client().Get(server.URL)
+	require.Nil(t, err)
+	resp.Body.Close()
+}
+
+func TestProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a forward proxy receives the absolute url of the target
+		proxied = r.URL.String()
+		_, _ = io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	options := DefaultOptions()
+	options.Proxy = proxy.URL
+	configure(t, options)
+
+	resp, err := Client().Get("http://pdtm.invalid/tools")
+	require.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, "proxied", string(body))
+	require.Equal(t, "http://pdtm.invalid/tools", proxied)
+
+	options.Proxy = "not a url"
+	require.NotNil(t, Configure(options))
+}
+
+func TestUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.UserAgent = "pdtm/v1.0.0"
+	configure(t, options)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.Nil(t, err)
+	req.Header.Set("User-Agent", "go-github")
+	resp, err := Client().Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, "pdtm/v1.0.0", userAgent)
+	require.Equal(t, "go-github", req.Header.Get("User-Agent"), "caller's request must not be modified")
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.Timeout = 50 * time.Millisecond
+	configure(t, options)
+
+	_, err := Client().Get(server.URL)
+	require.NotNil(t, err)
+}
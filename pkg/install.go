@@ -1,24 +1,21 @@
 package pkg
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"bytes"
-	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 
-	"github.com/google/go-github/github"
 	"github.com/logrusorgru/aurora/v4"
 	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg/asset"
 	ospath "github.com/projectdiscovery/pdtm/pkg/path"
 	"github.com/projectdiscovery/pdtm/pkg/types"
 	osutils "github.com/projectdiscovery/utils/os"
@@ -41,7 +38,7 @@ func Install(path string, tool types.Tool) error {
 	if err != nil {
 		return err
 	}
-	gologger.Info().Msgf("installed %s %s (%s)", tool.Name, version, au.BrightGreen("latest").String())
+	gologger.Info().Msgf("installed %s %s (%s)", tool.Name, version, versionLabel(tool))
 	return nil
 }
 
@@ -52,192 +49,230 @@ func GoInstall(path string, tool types.Tool) error {
 	}
 	gologger.Info().Msgf("installing %s with go install...", tool.Name)
 	printRequirementInfo(tool)
-	cmd := exec.Command("go", "install", "-v", fmt.Sprintf("github.com/projectdiscovery/%s/%s", tool.Name, tool.GoInstallPath))
-	cmd.Env = append(os.Environ(), "GOBIN="+path)
+	var version string
+	if tool.Pinned {
+		version = tool.Version
+	}
+	if err := goInstall(path, tool, version); err != nil {
+		return err
+	}
+	gologger.Info().Msgf("installed %s %s (%s)", tool.Name, tool.Version, versionLabel(tool))
+	return nil
+}
+
+// goInstall builds tool with go install at version, the default version of
+// the module when empty, and installs it side by side like release assets
+func goInstall(path string, tool types.Tool, version string) error {
+	module := strings.TrimSuffix(fmt.Sprintf("github.com/%s/%s/%s", tool.GetOwner(), tool.Repo, tool.GoInstallPath), "/")
+	if version != "" {
+		module += "@" + releaseTag(version)
+	}
+	stagingDir, err := newStagingDir(path, tool.Name)
+	if err != nil {
+		return err
+	}
+	defer removeStagingDir(tool.Name, stagingDir)
+	cmd := exec.Command("go", "install", "-v", module)
+	cmd.Env = append(os.Environ(), "GOBIN="+stagingDir)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("go install failed %s", string(output))
 	}
-	gologger.Info().Msgf("installed %s %s (%s)", tool.Name, tool.Version, au.BrightGreen("latest").String())
+	if err := commitStaged(stagingDir, path, tool, legacyVersion(path, tool)); err != nil {
+		return err
+	}
+	recordReceipt(path, tool, types.Go, "", "", nil)
 	return nil
 }
 
+// versionLabel describes the version being installed for log messages
+func versionLabel(tool types.Tool) string {
+	if tool.Pinned {
+		return au.BrightCyan("pinned").String()
+	}
+	return au.BrightGreen("latest").String()
+}
+
 func install(tool types.Tool, path string) (string, error) {
-	builder := &strings.Builder{}
-	builder.WriteString(tool.Name)
-	builder.WriteString("_")
-	builder.WriteString(strings.TrimPrefix(tool.Version, "v"))
-	builder.WriteString("_")
-	if strings.EqualFold(runtime.GOOS, "darwin") {
-		builder.WriteString("macOS")
-	} else {
-		builder.WriteString(runtime.GOOS)
-	}
-	builder.WriteString("_")
-	builder.WriteString(runtime.GOARCH)
-	var id int
-	var isZip, isTar bool
-loop:
-	for asset, assetID := range tool.Assets {
-		switch {
-		case strings.Contains(asset, ".zip"):
-			if strings.EqualFold(asset, builder.String()+".zip") {
-				id, _ = strconv.Atoi(assetID)
-				isZip = true
-				break loop
-			}
-		case strings.Contains(asset, ".tar.gz"):
-			if strings.EqualFold(asset, builder.String()+".tar.gz") {
-				id, _ = strconv.Atoi(assetID)
-				isTar = true
-				break loop
-			}
-		}
+	version, err := installWith(tool, path, fetchAsset)
+	// the staged download is discarded, so an asset that changed while
+	// resuming is downloaded again from the start
+	var resumeErr *types.ResumeError
+	if errors.As(err, &resumeErr) {
+		gologger.Verbose().Msgf("%s: %s, downloading again", tool.Name, err)
+		return installWith(tool, path, fetchAsset)
 	}
-	builder.Reset()
+	return version, err
+}
+
+// assetFetcher returns the content of the release asset with the given id
+// and its size, -1 when unknown
+type assetFetcher func(tool types.Tool, id int) (io.ReadCloser, int64, error)
 
-	// handle if id is zero (no asset found)
-	if id == 0 {
+// installWith installs the release asset of tool for the current platform
+// fetched with fetch
+func installWith(tool types.Tool, path string, fetch assetFetcher) (_ string, err error) {
+	releaseAsset, ok := asset.Find(tool)
+	if !ok {
 		return "", fmt.Errorf(types.ErrNoAssetFound, runtime.GOOS, runtime.GOARCH)
 	}
+	assetName := releaseAsset.Name
+	id, err := strconv.Atoi(releaseAsset.ID)
+	if err != nil {
+		return "", fmt.Errorf("invalid id %q of release asset %s", releaseAsset.ID, assetName)
+	}
 
-	_, rdurl, err := GithubClient().Repositories.DownloadReleaseAsset(context.Background(), types.Organization, tool.Repo, int64(id))
+	checksum, err := expectedChecksum(tool, assetName)
 	if err != nil {
-		if arlErr, ok := err.(*github.AbuseRateLimitError); ok {
-			// Provide user with more info regarding the rate limit
-			gologger.Error().Msgf("error for remaining request per hour: %s, RetryAfter: %s", err.Error(), arlErr.RetryAfter)
-		}
 		return "", err
 	}
 
-	resp, err := http.Get(rdurl)
+	body, size, err := fetch(tool, id)
 	if err != nil {
 		return "", err
 	}
-
 	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			gologger.Warning().Msgf("Error closing response body: %s", err)
+		if err := body.Close(); err != nil {
+			gologger.Warning().Msgf("%s: error closing response body: %s", tool.Name, err)
 		}
 	}()
-	if resp.StatusCode != 200 {
+	download := newProgressReader(body, tool.Name, assetName, size)
+	defer func() {
+		download.finish(err)
+	}()
+	reader := newChecksumReader(download)
+
+	// extract into a staging directory so that a failed download, a corrupt
+	// archive or a checksum mismatch never touches the installed binary
+	stagingDir, err := newStagingDir(path, tool.Name)
+	if err != nil {
 		return "", err
 	}
+	defer removeStagingDir(tool.Name, stagingDir)
 
-	switch {
-	case isZip:
-		err := downloadZip(resp.Body, tool.Name, path)
-		if err != nil {
-			return "", err
-		}
-	case isTar:
-		err := downloadTar(resp.Body, tool.Name, path)
-		if err != nil {
+	if err := extract(releaseAsset.Format, reader, tool, stagingDir); err != nil {
+		return "", err
+	}
+
+	if checksum != "" {
+		if err := reader.verify(assetName, checksum); err != nil {
 			return "", err
 		}
 	}
-	return tool.Version, nil
-}
+	assetChecksum, err := reader.digest()
+	if err != nil {
+		return "", err
+	}
 
-func downloadTar(reader io.Reader, toolName, path string) error {
-	gzipReader, err := gzip.NewReader(reader)
+	if err := commitStaged(stagingDir, path, tool, legacyVersion(path, tool)); err != nil {
+		return "", err
+	}
+	// the binary is in place, missing auxiliary files do not fail the install
+	files, err := installFiles(stagingDir, path, tool)
 	if err != nil {
-		return err
+		gologger.Warning().Msgf("%s: could not install auxiliary files: %s", tool.Name, err)
 	}
-	tarReader := tar.NewReader(gzipReader)
-	// iterate through the files in the archive
-	for {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		if !strings.EqualFold(strings.TrimSuffix(header.FileInfo().Name(), extIfFound), toolName) {
-			continue
-		}
-		// if the file is not a directory, extract it
-		if !header.FileInfo().IsDir() {
-			filePath := filepath.Join(path, header.FileInfo().Name())
-			if !strings.HasPrefix(filePath, filepath.Clean(path)+string(os.PathSeparator)) {
-				return err
-			}
+	recordReceipt(path, tool, types.Binary, assetName, assetChecksum, files)
+	return tool.Version, nil
+}
 
-			if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-				return err
-			}
+// fetchAsset downloads a release asset, overridden in tests to serve local archives
+var fetchAsset assetFetcher = downloadAsset
 
-			dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode())
-			if err != nil {
-				return err
-			}
-			defer func() {
-				if err := dstFile.Close(); err != nil {
-					gologger.Warning().Msgf("Error closing file: %s", err)
-				}
-			}()
-			// copy the file data from the archive
-			_, err = io.Copy(dstFile, tarReader)
-			if err != nil {
-				return err
-			}
-			// set the file permissions
-			err = os.Chmod(dstFile.Name(), 0755)
-			if err != nil {
-				return err
-			}
+// downloadAsset returns the content of the release asset with the given id
+// and its size, -1 when unknown
+func downloadAsset(tool types.Tool, id int) (io.ReadCloser, int64, error) {
+	ctx := withToolName(context.Background(), tool.Name)
+	if tool.DownloadURL != "" {
+		name, ok := assetName(tool, id)
+		if !ok {
+			return nil, 0, fmt.Errorf("asset %d of %s not found", id, tool.Name)
 		}
+		return downloader.Download(ctx, strings.TrimSuffix(tool.DownloadURL, "/")+"/"+url.PathEscape(name))
 	}
-	return nil
-}
-
-func downloadZip(reader io.Reader, toolName, path string) error {
-	buff := bytes.NewBuffer([]byte{})
-	size, err := io.Copy(buff, reader)
-	if err != nil {
-		return err
+	// rate limited requests would fail until the reset, release assets of
+	// public repositories are downloadable without the api
+	if exhausted, wait := githubRateLimits.host(githubSettings().apiURL.Host).exhausted(); exhausted && wait > maxRateLimitWait {
+		gologger.Verbose().Msgf("%s: GitHub api rate limit exhausted, downloading from the public release url", tool.Name)
+		return downloadPublicAsset(tool, id)
 	}
-	zipReader, err := zip.NewReader(bytes.NewReader(buff.Bytes()), size)
+	rc, rdurl, err := GithubClient().Repositories.DownloadReleaseAsset(ctx, tool.GetOwner(), tool.Repo, int64(id))
 	if err != nil {
-		return err
-	}
-	for _, f := range zipReader.File {
-		if !strings.EqualFold(strings.TrimSuffix(f.Name, extIfFound), toolName) {
-			continue
-		}
-		filePath := filepath.Join(path, f.Name)
-		if !strings.HasPrefix(filePath, filepath.Clean(path)+string(os.PathSeparator)) {
-			return err
-		}
-
-		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-			return err
+		if isRateLimited(err) {
+			gologger.Warning().Msgf("%s: %s, downloading from the public release url", tool.Name, githubError(err))
+			return downloadPublicAsset(tool, id)
 		}
+		return nil, 0, err
+	}
+	if rc != nil {
+		return rc, -1, nil
+	}
+	return downloader.Download(ctx, rdurl)
+}
 
-		dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
-		}
+// downloadPublicAsset downloads the release asset with the given id from the
+// public release download url of its repository on the configured GitHub
+func downloadPublicAsset(tool types.Tool, id int) (io.ReadCloser, int64, error) {
+	name, ok := assetName(tool, id)
+	if !ok {
+		return nil, 0, fmt.Errorf("asset %d of %s not found", id, tool.Name)
+	}
+	ctx := withToolName(context.Background(), tool.Name)
+	downloadURL := githubSettings().downloadURL
+	publicURL := func(tag string) string {
+		return fmt.Sprintf("%s/%s/%s/releases/download/%s/%s", downloadURL, tool.GetOwner(), tool.Repo, url.PathEscape(tag), url.PathEscape(name))
+	}
+	rc, size, err := downloader.Download(ctx, publicURL(releaseTag(tool.Version)))
+	var statusErr *types.HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		// some projects tag their releases without the v prefix
+		return downloader.Download(ctx, publicURL(strings.TrimPrefix(tool.Version, "v")))
+	}
+	return rc, size, err
+}
 
-		fileInArchive, err := f.Open()
-		if err != nil {
-			return err
+// assetName returns the name of the release asset of tool with the given id
+func assetName(tool types.Tool, id int) (string, bool) {
+	assetID := strconv.Itoa(id)
+	for name, value := range tool.Assets {
+		if value == assetID {
+			return name, true
 		}
+	}
+	return "", false
+}
 
-		if _, err := io.Copy(dstFile, fileInArchive); err != nil {
-			return err
-		}
-		err = os.Chmod(dstFile.Name(), 0755)
-		if err != nil {
+// DownloadReleaseAsset writes the release asset name of tool to writer. The
+// asset is verified against the release checksums when they are published.
+func DownloadReleaseAsset(tool types.Tool, name string, writer io.Writer) error {
+	assetID, ok := tool.Assets[name]
+	if !ok {
+		return fmt.Errorf("asset %s of %s not found", name, tool.Name)
+	}
+	id, err := strconv.Atoi(assetID)
+	if err != nil {
+		return fmt.Errorf("invalid id %q of release asset %s", assetID, name)
+	}
+	var checksum string
+	if name != checksumAssetName(tool) {
+		if checksum, err = expectedChecksum(tool, name); err != nil {
 			return err
 		}
-
-		if err := dstFile.Close(); err != nil {
-			gologger.Warning().Msgf("Error closing file: %s", err)
-		}
-		if err := fileInArchive.Close(); err != nil {
-			gologger.Warning().Msgf("Error closing file in archive: %s", err)
+	}
+	body, _, err := fetchAsset(tool, id)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			gologger.Warning().Msgf("%s: error closing response body: %s", tool.Name, err)
 		}
+	}()
+	reader := newChecksumReader(body)
+	if _, err := io.Copy(writer, reader); err != nil {
+		return err
+	}
+	if checksum != "" {
+		return reader.verify(name, checksum)
 	}
 	return nil
 }
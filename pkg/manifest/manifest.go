@@ -0,0 +1,265 @@
+// Package manifest implements the pdtm.yaml project manifest and the
+// pdtm.lock lockfile resolved from it
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/utils"
+	"github.com/projectdiscovery/pdtm/pkg/version"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+const latest = "latest"
+
+// Manifest lists the tools a project depends on
+type Manifest struct {
+	Tools []Requirement `yaml:"tools"`
+}
+
+// Requirement is a tool and the version it must be installed at
+type Requirement struct {
+	Name string `yaml:"name"`
+	// Version is an exact version, a semver constraint such as "~3.1" or
+	// empty for the latest release
+	Version string `yaml:"version,omitempty"`
+	// SkipChecksum installs a projectdiscovery release that does not
+	// publish a checksums file without verification
+	SkipChecksum bool `yaml:"skip_checksum,omitempty"`
+}
+
+// Lockfile records the exact releases resolved from a manifest
+type Lockfile struct {
+	Tools []LockedTool `yaml:"tools"`
+}
+
+// LockedTool is a tool release resolved from a manifest requirement
+type LockedTool struct {
+	Name  string `yaml:"name"`
+	Owner string `yaml:"owner,omitempty"`
+	Repo  string `yaml:"repo"`
+	// Constraint is the manifest version the release was resolved from
+	Constraint    string              `yaml:"constraint,omitempty"`
+	Version       string              `yaml:"version"`
+	InstallType   types.InstallType   `yaml:"install_type,omitempty"`
+	GoInstallPath string              `yaml:"go_install_path,omitempty"`
+	AssetTemplate string              `yaml:"asset_template,omitempty"`
+	OSAliases     map[string][]string `yaml:"os_aliases,omitempty"`
+	ArchAliases   map[string][]string `yaml:"arch_aliases,omitempty"`
+	ArchiveFormat string              `yaml:"archive_format,omitempty"`
+	Files         []types.ToolFile    `yaml:"files,omitempty"`
+	SkipChecksum  bool                `yaml:"skip_checksum,omitempty"`
+	Assets        []LockedAsset       `yaml:"assets"`
+}
+
+// LockedAsset is a release archive and its sha256 digest
+type LockedAsset struct {
+	Name   string `yaml:"name"`
+	ID     string `yaml:"id"`
+	SHA256 string `yaml:"sha256,omitempty"`
+}
+
+// LoadManifest reads a manifest from file
+func LoadManifest(file string) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := load(file, manifest); err != nil {
+		return nil, err
+	}
+	for _, requirement := range manifest.Tools {
+		if requirement.Name == "" {
+			return nil, fmt.Errorf("%s: tool without name", file)
+		}
+	}
+	return manifest, nil
+}
+
+// LoadLockfile reads a lockfile from file
+func LoadLockfile(file string) (*Lockfile, error) {
+	lockfile := &Lockfile{}
+	if err := load(file, lockfile); err != nil {
+		return nil, err
+	}
+	return lockfile, nil
+}
+
+func load(file string, v interface{}) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			gologger.Warning().Msgf("Error closing file: %s", err)
+		}
+	}()
+	return fileutil.UnmarshalFromReader(fileutil.YAML, f, v)
+}
+
+// Save writes the lockfile to file
+func (l *Lockfile) Save(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := fileutil.MarshalToWriter(fileutil.YAML, f, l); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Satisfies reports whether the lockfile was resolved from exactly the
+// requirements of manifest
+func (l *Lockfile) Satisfies(manifest *Manifest) bool {
+	if len(l.Tools) != len(manifest.Tools) {
+		return false
+	}
+	locked := make(map[string]LockedTool, len(l.Tools))
+	for _, tool := range l.Tools {
+		locked[strings.ToLower(tool.Name)] = tool
+	}
+	for _, requirement := range manifest.Tools {
+		tool, ok := locked[strings.ToLower(requirement.Name)]
+		if !ok || tool.Constraint != requirement.Version || tool.SkipChecksum != requirement.SkipChecksum {
+			return false
+		}
+	}
+	return true
+}
+
+// Tool returns the locked release as a tool that installs with the locked
+// checksums
+func (t LockedTool) Tool() types.Tool {
+	tool := types.Tool{
+		Name:          t.Name,
+		Owner:         t.Owner,
+		Repo:          t.Repo,
+		Version:       t.Version,
+		InstallType:   t.InstallType,
+		GoInstallPath: t.GoInstallPath,
+		AssetTemplate: t.AssetTemplate,
+		OSAliases:     t.OSAliases,
+		ArchAliases:   t.ArchAliases,
+		ArchiveFormat: t.ArchiveFormat,
+		Files:         t.Files,
+		SkipChecksum:  t.SkipChecksum,
+		Assets:        make(map[string]string, len(t.Assets)),
+		Pinned:        true,
+	}
+	for _, asset := range t.Assets {
+		tool.Assets[asset.Name] = asset.ID
+		if asset.SHA256 == "" {
+			continue
+		}
+		if tool.Checksums == nil {
+			tool.Checksums = make(map[string]string)
+		}
+		tool.Checksums[asset.Name] = asset.SHA256
+	}
+	return tool
+}
+
+// Resolve resolves every requirement of manifest against the tool list and
+// returns the lockfile describing the selected releases
+func Resolve(manifest *Manifest, tools []types.Tool) (*Lockfile, error) {
+	lockfile := &Lockfile{}
+	for _, requirement := range manifest.Tools {
+		i, ok := utils.Contains(tools, requirement.Name)
+		if !ok {
+			return nil, fmt.Errorf("%s not found in the list", requirement.Name)
+		}
+		release, err := resolveRequirement(requirement, tools[i])
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %s %s: %w", requirement.Name, requirement.Version, err)
+		}
+		locked, err := lock(requirement, release)
+		if err != nil {
+			return nil, fmt.Errorf("could not lock %s %s: %w", requirement.Name, release.Version, err)
+		}
+		lockfile.Tools = append(lockfile.Tools, locked)
+	}
+	return lockfile, nil
+}
+
+// resolveRequirement returns the release of tool selected by the requirement
+func resolveRequirement(requirement Requirement, tool types.Tool) (types.Tool, error) {
+	requested := strings.TrimSpace(requirement.Version)
+	if requested == "" || strings.EqualFold(requested, latest) {
+		return tool, nil
+	}
+	if exact, err := semver.StrictNewVersion(strings.TrimPrefix(requested, "v")); err == nil {
+		if version.Equal(exact.String(), tool.Version) {
+			return tool, nil
+		}
+		return pkg.GetRelease(tool, exact.String())
+	}
+
+	constraint, err := semver.NewConstraint(requested)
+	if err != nil {
+		return tool, err
+	}
+	releases, err := pkg.ListReleases(tool)
+	if err != nil {
+		return tool, err
+	}
+	var selected *types.Tool
+	var selectedVersion *semver.Version
+	for i, release := range releases {
+		v, err := version.Parse(release.Version)
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+		if selectedVersion == nil || v.GreaterThan(selectedVersion) {
+			selected, selectedVersion = &releases[i], v
+		}
+	}
+	if selected == nil {
+		return tool, fmt.Errorf("no release matches %s", requested)
+	}
+	return *selected, nil
+}
+
+// lock records the release archives of tool together with their checksums
+func lock(requirement Requirement, tool types.Tool) (LockedTool, error) {
+	checksums, err := pkg.FetchChecksums(tool)
+	if err != nil {
+		return LockedTool{}, err
+	}
+	if checksums == nil {
+		gologger.Warning().Msgf("%s %s does not publish checksums, locking without them", tool.Name, tool.Version)
+	}
+
+	locked := LockedTool{
+		Name:          tool.Name,
+		Owner:         tool.Owner,
+		Repo:          tool.Repo,
+		Constraint:    requirement.Version,
+		Version:       tool.Version,
+		InstallType:   tool.InstallType,
+		GoInstallPath: tool.GoInstallPath,
+		AssetTemplate: tool.AssetTemplate,
+		OSAliases:     tool.OSAliases,
+		ArchAliases:   tool.ArchAliases,
+		ArchiveFormat: tool.ArchiveFormat,
+		Files:         tool.Files,
+		SkipChecksum:  requirement.SkipChecksum,
+	}
+	for name, id := range tool.Assets {
+		if strings.HasSuffix(name, "_checksums.txt") {
+			continue
+		}
+		locked.Assets = append(locked.Assets, LockedAsset{Name: name, ID: id, SHA256: checksums[name]})
+	}
+	// keep the lockfile stable across resolutions
+	sort.Slice(locked.Assets, func(i, j int) bool {
+		return locked.Assets[i].Name < locked.Assets[j].Name
+	})
+	return locked, nil
+}
@@ -0,0 +1,75 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockfileSatisfies(t *testing.T) {
+	lockfile := &Lockfile{Tools: []LockedTool{
+		{Name: "nuclei", Constraint: "~3.1", Version: "3.1.10"},
+		{Name: "httpx", Version: "1.6.0"},
+	}}
+
+	require.True(t, lockfile.Satisfies(&Manifest{Tools: []Requirement{
+		{Name: "httpx"},
+		{Name: "Nuclei", Version: "~3.1"},
+	}}))
+	require.False(t, lockfile.Satisfies(&Manifest{Tools: []Requirement{
+		{Name: "httpx"},
+		{Name: "nuclei", Version: "~3.2"},
+	}}), "changed constraint requires resolving again")
+	require.False(t, lockfile.Satisfies(&Manifest{Tools: []Requirement{
+		{Name: "httpx"},
+	}}), "removed tool requires resolving again")
+	require.False(t, lockfile.Satisfies(&Manifest{Tools: []Requirement{
+		{Name: "httpx", SkipChecksum: true},
+		{Name: "nuclei", Version: "~3.1"},
+	}}), "changed checksum opt-out requires resolving again")
+}
+
+func TestLockedToolTool(t *testing.T) {
+	locked := LockedTool{
+		Name:    "dnsx",
+		Repo:    "dnsx",
+		Version: "1.1.1",
+		Assets: []LockedAsset{
+			{Name: "dnsx_1.1.1_linux_amd64.zip", ID: "79344859", SHA256: "abc"},
+			{Name: "dnsx_1.1.1_macOS_arm64.zip", ID: "79344856"},
+		},
+	}
+
+	tool := locked.Tool()
+	require.True(t, tool.Pinned)
+	require.Equal(t, "79344856", tool.Assets["dnsx_1.1.1_macOS_arm64.zip"])
+	require.Equal(t, map[string]string{"dnsx_1.1.1_linux_amd64.zip": "abc"}, tool.Checksums)
+	require.False(t, tool.SkipChecksum)
+
+	locked.SkipChecksum = true
+	require.True(t, locked.Tool().SkipChecksum)
+}
+
+func TestLockfileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	manifestFile := filepath.Join(dir, "pdtm.yaml")
+	require.NoError(t, os.WriteFile(manifestFile, []byte("tools:\n  - name: nuclei\n    version: ~3.1\n  - name: httpx\n"), 0644))
+
+	manifest, err := LoadManifest(manifestFile)
+	require.NoError(t, err)
+	require.Equal(t, []Requirement{{Name: "nuclei", Version: "~3.1"}, {Name: "httpx"}}, manifest.Tools)
+
+	lockfile := &Lockfile{Tools: []LockedTool{
+		{Name: "nuclei", Repo: "nuclei", Constraint: "~3.1", Version: "3.1.10", Assets: []LockedAsset{{Name: "nuclei_3.1.10_linux_amd64.zip", ID: "1", SHA256: "abc"}}},
+		{Name: "httpx", Repo: "httpx", Version: "1.6.0", Assets: []LockedAsset{{Name: "httpx_1.6.0_linux_amd64.zip", ID: "2"}}},
+	}}
+	lockFile := filepath.Join(dir, "pdtm.lock")
+	require.NoError(t, lockfile.Save(lockFile))
+
+	loaded, err := LoadLockfile(lockFile)
+	require.NoError(t, err)
+	require.Equal(t, lockfile, loaded)
+	require.True(t, loaded.Satisfies(manifest))
+}
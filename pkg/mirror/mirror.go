@@ -0,0 +1,300 @@
+// Package mirror implements a pdtm api server that mirrors the tool list of
+// upstream sources and serves their release assets from a local cache
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+const (
+	// toolsFile keeps the last tool list so the server starts without upstream
+	toolsFile = "tools.json"
+	// assetsDir holds the cached release assets as assets/<tool>/<version>/<asset>
+	assetsDir = "assets"
+)
+
+// Options configures a mirror server
+type Options struct {
+	// Tools loads the tool list from upstream
+	Tools func() ([]types.Tool, error)
+	// Dir stores the tool list and the cached release assets
+	Dir string
+	// Refresh is the interval the tool list is reloaded at, never when zero
+	Refresh time.Duration
+	// URL is the public base url of the server, derived from the requests
+	// when empty
+	URL string
+}
+
+// Server serves the pdtm api and release assets. Assets are downloaded from
+// upstream on first request and served from disk afterwards.
+type Server struct {
+	options Options
+
+	mutex sync.RWMutex
+	tools []types.Tool
+
+	// downloads serializes concurrent requests for the same asset
+	downloads sync.Map
+}
+
+// New returns a server for options, Load must be called before serving
+func New(options Options) (*Server, error) {
+	if options.Tools == nil {
+		return nil, errors.New("no upstream tool list")
+	}
+	if options.Dir == "" {
+		return nil, errors.New("no mirror directory")
+	}
+	if err := os.MkdirAll(options.Dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &Server{options: options}, nil
+}
+
+// Load reloads the tool list from upstream. The last tool list is kept when
+// upstream is unavailable and read from disk when there is none yet.
+func (s *Server) Load() error {
+	tools, err := s.options.Tools()
+	if err == nil && len(tools) == 0 {
+		err = errors.New("upstream returned no tools")
+	}
+	if err != nil {
+		if s.Tools() != nil {
+			return err
+		}
+		if stored, loadErr := s.loadTools(); loadErr == nil {
+			gologger.Warning().Msgf("upstream is unavailable, serving stored tool list: %s", err)
+			s.setTools(stored)
+			return nil
+		}
+		return err
+	}
+	s.setTools(tools)
+	return s.saveTools(tools)
+}
+
+// Tools returns the served tool list as loaded from upstream
+func (s *Server) Tools() []types.Tool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.tools
+}
+
+func (s *Server) setTools(tools []types.Tool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tools = tools
+}
+
+func (s *Server) loadTools() ([]types.Tool, error) {
+	data, err := os.ReadFile(filepath.Join(s.options.Dir, toolsFile))
+	if err != nil {
+		return nil, err
+	}
+	var tools []types.Tool
+	if err := json.Unmarshal(data, &tools); err != nil {
+		return nil, err
+	}
+	return tools, nil
+}
+
+func (s *Server) saveTools(tools []types.Tool) error {
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.options.Dir, toolsFile), data, 0644)
+}
+
+// ListenAndServe loads the tool list and serves on addr until ctx is done,
+// reloading the tool list every Refresh interval
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	if s.options.Refresh > 0 {
+		go s.refresh(ctx)
+	}
+
+	server := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	gologger.Info().Msgf("serving %d tools on %s", len(s.Tools()), addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) refresh(ctx context.Context) {
+	ticker := time.NewTicker(s.options.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(); err != nil {
+				gologger.Warning().Msgf("could not refresh tool list: %s", err)
+				continue
+			}
+			gologger.Verbose().Msgf("refreshed tool list, %d tools", len(s.Tools()))
+		}
+	}
+}
+
+// Handler returns the handler serving the pdtm api under /api/v1/tools/
+// and the release assets under /assets/
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/tools/{$}", s.handleTools)
+	mux.HandleFunc("GET /api/v1/tools/{name}", s.handleTool)
+	mux.HandleFunc("GET /assets/{tool}/{version}/{asset}", s.handleAsset)
+	return mux
+}
+
+func (s *Server) handleTools(w http.ResponseWriter, r *http.Request) {
+	tools := s.Tools()
+	served := make([]types.Tool, 0, len(tools))
+	for _, tool := range tools {
+		served = append(served, s.mirrored(r, tool))
+	}
+	writeJSON(w, served)
+}
+
+func (s *Server) handleTool(w http.ResponseWriter, r *http.Request) {
+	tool, ok := s.lookup(r.PathValue("name"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	tool = s.mirrored(r, tool)
+	// the pdtm api returns nuclei together with its templates
+	if tool.Name == "nuclei" {
+		writeJSON(w, types.NucleiData{Tools: []types.Tool{tool}})
+		return
+	}
+	writeJSON(w, tool)
+}
+
+func (s *Server) lookup(name string) (types.Tool, bool) {
+	for _, tool := range s.Tools() {
+		if strings.EqualFold(tool.Name, name) {
+			return tool, true
+		}
+	}
+	return types.Tool{}, false
+}
+
+// mirrored returns tool with its assets downloaded through the server
+func (s *Server) mirrored(r *http.Request, tool types.Tool) types.Tool {
+	tool.DownloadURL = fmt.Sprintf("%s/%s/%s/%s", s.baseURL(r), assetsDir, url.PathEscape(tool.Name), url.PathEscape(tool.Version))
+	return tool
+}
+
+// baseURL returns the public url of the server
+func (s *Server) baseURL(r *http.Request) string {
+	if s.options.URL != "" {
+		return strings.TrimSuffix(s.options.URL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+func (s *Server) handleAsset(w http.ResponseWriter, r *http.Request) {
+	toolName, version, name := r.PathValue("tool"), r.PathValue("version"), r.PathValue("asset")
+	assetPath := filepath.Join(assetsDir, toolName, version, name)
+	if strings.ContainsAny(toolName+version+name, `/\`) || !filepath.IsLocal(assetPath) {
+		http.NotFound(w, r)
+		return
+	}
+	assetPath = filepath.Join(s.options.Dir, assetPath)
+
+	if err := s.cache(toolName, version, name, assetPath); err != nil {
+		if errors.Is(err, errNotMirrored) {
+			http.NotFound(w, r)
+			return
+		}
+		gologger.Error().Msgf("%s: could not download %s: %s", toolName, name, err)
+		http.Error(w, "could not download asset from upstream", http.StatusBadGateway)
+		return
+	}
+	// ServeFile answers range requests, so interrupted downloads resume
+	http.ServeFile(w, r, assetPath)
+}
+
+var errNotMirrored = errors.New("asset is not mirrored")
+
+// cache downloads the release asset to assetPath unless it is cached.
+// Only assets of the current release of a tool can be downloaded, older
+// ones are served while they are cached.
+func (s *Server) cache(toolName, version, name, assetPath string) error {
+	value, _ := s.downloads.LoadOrStore(assetPath, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, err := os.Stat(assetPath); err == nil {
+		return nil
+	}
+	tool, ok := s.lookup(toolName)
+	if !ok || tool.Version != version {
+		return errNotMirrored
+	}
+	if _, ok := tool.Assets[name]; !ok {
+		return errNotMirrored
+	}
+
+	gologger.Info().Msgf("%s: downloading %s from upstream", tool.Name, name)
+	if err := os.MkdirAll(filepath.Dir(assetPath), os.ModePerm); err != nil {
+		return err
+	}
+	// the asset is only moved in once complete and verified
+	tmpFile, err := os.CreateTemp(filepath.Dir(assetPath), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+	if err := pkg.DownloadReleaseAsset(tool, name, tmpFile); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), assetPath)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		gologger.Warning().Msgf("could not write response: %s", err)
+	}
+}
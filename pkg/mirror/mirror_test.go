@@ -0,0 +1,217 @@
+package mirror
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	archiveName   = "dnsx_1.1.1_linux_amd64.tar.gz"
+	checksumsName = "dnsx_1.1.1_checksums.txt"
+)
+
+// upstream serves the release assets of dnsx and counts the downloads
+type upstream struct {
+	*httptest.Server
+	archive   []byte
+	downloads atomic.Int32
+}
+
+func newUpstream(t *testing.T, archive []byte) *upstream {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte("archive"))
+	checksums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archiveName)
+	u := &upstream{archive: archive}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + archiveName:
+			u.downloads.Add(1)
+			_, _ = w.Write(u.archive)
+		case "/" + checksumsName:
+			_, _ = io.WriteString(w, checksums)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+func (u *upstream) tool() types.Tool {
+	return types.Tool{
+		Name:        "dnsx",
+		Repo:        "dnsx",
+		Version:     "1.1.1",
+		InstallType: types.Binary,
+		Assets:      map[string]string{archiveName: "1", checksumsName: "2"},
+		DownloadURL: u.URL,
+	}
+}
+
+func newServer(t *testing.T, tools func() ([]types.Tool, error)) (*Server, *httptest.Server) {
+	t.Helper()
+
+	server, err := New(Options{Tools: tools, Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, server.Load())
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return server, httpServer
+}
+
+func get(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, body
+}
+
+func TestToolList(t *testing.T) {
+	up := newUpstream(t, []byte("archive"))
+	_, server := newServer(t, func() ([]types.Tool, error) {
+		return []types.Tool{up.tool()}, nil
+	})
+
+	tools, err := utils.FetchToolListFrom(server.URL)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	require.Equal(t, "1.1.1", tools[0].Version)
+	require.Equal(t, server.URL+"/assets/dnsx/1.1.1", tools[0].DownloadURL)
+
+	status, body := get(t, server.URL+"/api/v1/tools/dnsx")
+	require.Equal(t, http.StatusOK, status)
+	var tool types.Tool
+	require.NoError(t, json.Unmarshal(body, &tool))
+	require.Equal(t, "dnsx", tool.Name)
+
+	status, _ = get(t, server.URL+"/api/v1/tools/unknown")
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestNucleiShape(t *testing.T) {
+	_, server := newServer(t, func() ([]types.Tool, error) {
+		return []types.Tool{{Name: "nuclei", Repo: "nuclei", Version: "3.0.0"}}, nil
+	})
+
+	_, body := get(t, server.URL+"/api/v1/tools/nuclei")
+	var data types.NucleiData
+	require.NoError(t, json.Unmarshal(body, &data))
+	require.Len(t, data.Tools, 1)
+	require.Equal(t, "nuclei", data.Tools[0].Name)
+}
+
+func TestAssetDownloadedOnce(t *testing.T) {
+	up := newUpstream(t, []byte("archive"))
+	_, server := newServer(t, func() ([]types.Tool, error) {
+		return []types.Tool{up.tool()}, nil
+	})
+
+	for range 3 {
+		status, body := get(t, server.URL+"/assets/dnsx/1.1.1/"+archiveName)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "archive", string(body))
+	}
+	require.Equal(t, int32(1), up.downloads.Load())
+
+	status, _ := get(t, server.URL+"/assets/dnsx/1.1.1/unknown.tar.gz")
+	require.Equal(t, http.StatusNotFound, status)
+	status, _ = get(t, server.URL+"/assets/dnsx/1.0.0/"+archiveName)
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestCorruptAssetNotCached(t *testing.T) {
+	up := newUpstream(t, []byte("tampered"))
+	_, server := newServer(t, func() ([]types.Tool, error) {
+		return []types.Tool{up.tool()}, nil
+	})
+
+	status, _ := get(t, server.URL+"/assets/dnsx/1.1.1/"+archiveName)
+	require.Equal(t, http.StatusBadGateway, status)
+
+	up.archive = []byte("archive")
+	status, body := get(t, server.URL+"/assets/dnsx/1.1.1/"+archiveName)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "archive", string(body))
+}
+
+func TestLoadKeepsToolList(t *testing.T) {
+	up := newUpstream(t, []byte("archive"))
+	available := true
+	tools := func() ([]types.Tool, error) {
+		if !available {
+			return nil, errors.New("upstream unavailable")
+		}
+		return []types.Tool{up.tool()}, nil
+	}
+	server, _ := newServer(t, tools)
+
+	available = false
+	require.Error(t, server.Load())
+	require.Len(t, server.Tools(), 1)
+
+	// a restarted server reads the stored tool list
+	restarted, err := New(Options{Tools: tools, Dir: server.options.Dir})
+	require.NoError(t, err)
+	require.NoError(t, restarted.Load())
+	require.Len(t, restarted.Tools(), 1)
+}
+
+func TestPinnedInstallBypassesMirror(t *testing.T) {
+	up := newUpstream(t, []byte("archive"))
+	_, server := newServer(t, func() ([]types.Tool, error) {
+		return []types.Tool{up.tool()}, nil
+	})
+
+	// GitHub serves the release pinned to an older version than the mirror
+	const pinnedArchive = "dnsx_1.0.0_linux_amd64.tar.gz"
+	sum := sha256.Sum256([]byte("archive 1.0.0"))
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/projectdiscovery/dnsx/releases/tags/v1.0.0":
+			_, _ = fmt.Fprintf(w, `{"tag_name":"v1.0.0","assets":[{"id":10,"name":%q},{"id":11,"name":"dnsx_1.0.0_checksums.txt"}]}`, pinnedArchive)
+		case "/api/v3/repos/projectdiscovery/dnsx/releases/assets/10":
+			_, _ = io.WriteString(w, "archive 1.0.0")
+		case "/api/v3/repos/projectdiscovery/dnsx/releases/assets/11":
+			_, _ = fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), pinnedArchive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(github.Close)
+	require.NoError(t, pkg.ConfigureGithub(pkg.GithubOptions{APIURL: github.URL}))
+	t.Cleanup(func() {
+		require.NoError(t, pkg.ConfigureGithub(pkg.GithubOptions{}))
+	})
+
+	tools, err := utils.FetchToolListFrom(server.URL)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	pinned, err := pkg.GetRelease(tools[0], "1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0", pinned.Version)
+	require.Empty(t, pinned.DownloadURL, "the mirror only serves the latest release")
+
+	var archive bytes.Buffer
+	require.NoError(t, pkg.DownloadReleaseAsset(pinned, pinnedArchive, &archive))
+	require.Equal(t, "archive 1.0.0", archive.String())
+	require.Zero(t, up.downloads.Load())
+}
@@ -54,7 +54,26 @@ func GetOsData() string {
 	return "[OS: " + strings.ToUpper(os) + "] [ARCH: " + strings.ToUpper(arc) + "] [GO: " + goVersion + "]"
 }
 
+// GetExecutablePath returns the binary of toolName installed in path. For
+// tools installed side by side it is the active version behind the shim.
 func GetExecutablePath(path, toolName string) (string, bool) {
+	entryPath, exists := GetEntryPath(path, toolName)
+	if !exists {
+		return entryPath, false
+	}
+	if activePath, ok := ActiveExecutablePath(path, toolName); ok {
+		return activePath, true
+	}
+	return entryPath, true
+}
+
+// GetEntryPath returns the file of toolName in path, the shim of tools
+// installed side by side or the binary itself
+func GetEntryPath(path, toolName string) (string, bool) {
+	return findExecutable(path, toolName)
+}
+
+func findExecutable(path, toolName string) (string, bool) {
 	basePath := filepath.Join(path, toolName)
 	for _, ext := range CommonExtensions {
 		executablePath := basePath + ext
@@ -0,0 +1,49 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// VersionsDir holds the versions of the tools installed side by side as
+	// <path>/versions/<tool>/<version>/<binary>
+	VersionsDir = "versions"
+	// ActiveFile names the active version of a tool within its versions directory
+	ActiveFile = "active"
+)
+
+// ToolVersionsDir returns the directory holding the versions of toolName
+func ToolVersionsDir(path, toolName string) string {
+	return filepath.Join(path, VersionsDir, toolName)
+}
+
+// VersionDir returns the directory holding version of toolName
+func VersionDir(path, toolName, version string) string {
+	return filepath.Join(ToolVersionsDir(path, toolName), strings.TrimPrefix(version, "v"))
+}
+
+// ActiveVersion returns the active version of toolName installed in path
+func ActiveVersion(path, toolName string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(ToolVersionsDir(path, toolName), ActiveFile))
+	if err != nil {
+		return "", false
+	}
+	version := strings.TrimSpace(string(data))
+	return version, version != ""
+}
+
+// VersionExecutablePath returns the binary of version of toolName
+func VersionExecutablePath(path, toolName, version string) (string, bool) {
+	return findExecutable(VersionDir(path, toolName, version), toolName)
+}
+
+// ActiveExecutablePath returns the binary of the active version of toolName
+func ActiveExecutablePath(path, toolName string) (string, bool) {
+	version, ok := ActiveVersion(path, toolName)
+	if !ok {
+		return "", false
+	}
+	return VersionExecutablePath(path, toolName, version)
+}
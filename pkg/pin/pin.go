@@ -0,0 +1,60 @@
+// Package pin persists the versions tools are pinned to
+package pin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Pins maps tool names to the version they are pinned to
+type Pins map[string]string
+
+// Load reads pins from file, a missing file yields no pins
+func Load(file string) (Pins, error) {
+	pins := make(Pins)
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return pins, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &pins); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// Save writes pins to file
+func (p Pins) Save(file string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0644)
+}
+
+// Get returns the version toolName is pinned to
+func (p Pins) Get(toolName string) (string, bool) {
+	version, ok := p[strings.ToLower(toolName)]
+	return version, ok
+}
+
+// Set pins toolName to version
+func (p Pins) Set(toolName, version string) {
+	p[strings.ToLower(toolName)] = strings.TrimPrefix(version, "v")
+}
+
+// Delete removes the pin of toolName, reporting whether it was pinned
+func (p Pins) Delete(toolName string) bool {
+	if _, ok := p.Get(toolName); !ok {
+		return false
+	}
+	delete(p, strings.ToLower(toolName))
+	return true
+}
@@ -0,0 +1,28 @@
+package pin
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinsRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pdtm", "pins.json")
+
+	pins, err := Load(file)
+	require.NoError(t, err)
+	require.Empty(t, pins)
+
+	pins.Set("Nuclei", "v3.1.0")
+	require.NoError(t, pins.Save(file))
+
+	pins, err = Load(file)
+	require.NoError(t, err)
+	version, ok := pins.Get("nuclei")
+	require.True(t, ok)
+	require.Equal(t, "3.1.0", version)
+
+	require.True(t, pins.Delete("NUCLEI"))
+	require.False(t, pins.Delete("nuclei"))
+}
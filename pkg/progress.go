@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between two progress events of a download
+const progressInterval = 100 * time.Millisecond
+
+// ProgressEvent describes the progress of a release asset download
+type ProgressEvent struct {
+	Tool  string
+	Asset string
+	// Current is the number of bytes downloaded so far
+	Current int64
+	// Total is the asset size taken from Content-Length, -1 when unknown
+	Total int64
+	// Rate is the average download rate in bytes per second
+	Rate float64
+	// Done is set on the last event of a download, Err is set when it failed
+	Done bool
+	Err  error
+}
+
+// Progress receives download progress events. Concurrent downloads report
+// their events from different goroutines.
+type Progress interface {
+	Report(event ProgressEvent)
+}
+
+// ProgressFunc adapts a function to the Progress interface
+type ProgressFunc func(event ProgressEvent)
+
+func (f ProgressFunc) Report(event ProgressEvent) {
+	f(event)
+}
+
+var (
+	progress      Progress
+	progressMutex sync.RWMutex
+)
+
+// SetProgress sets the receiver of download progress events, nil disables
+// progress reporting
+func SetProgress(p Progress) {
+	progressMutex.Lock()
+	defer progressMutex.Unlock()
+	progress = p
+}
+
+func currentProgress() Progress {
+	progressMutex.RLock()
+	defer progressMutex.RUnlock()
+	return progress
+}
+
+// progressReader reports the bytes read from a download
+type progressReader struct {
+	reader   io.Reader
+	progress Progress
+	event    ProgressEvent
+	start    time.Time
+	last     time.Time
+}
+
+// newProgressReader wraps reader, reporting the download of asset of tool
+// to the current Progress. total is -1 when the size is unknown.
+func newProgressReader(reader io.Reader, tool, asset string, total int64) *progressReader {
+	p := &progressReader{
+		reader:   reader,
+		progress: currentProgress(),
+		event:    ProgressEvent{Tool: tool, Asset: asset, Total: total},
+		start:    time.Now(),
+	}
+	p.report()
+	return p
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.event.Current += int64(n)
+	if time.Since(p.last) >= progressInterval {
+		p.report()
+	}
+	return n, err
+}
+
+// finish reports the end of the download, err is the error it failed with
+func (p *progressReader) finish(err error) {
+	p.event.Done, p.event.Err = true, err
+	p.report()
+}
+
+func (p *progressReader) report() {
+	if p.progress == nil {
+		return
+	}
+	p.last = time.Now()
+	if elapsed := p.last.Sub(p.start).Seconds(); elapsed > 0 {
+		p.event.Rate = float64(p.event.Current) / elapsed
+	}
+	p.progress.Report(p.event)
+}
@@ -0,0 +1,67 @@
+package pkg
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// recordProgress collects the progress events reported during the test
+func recordProgress(t *testing.T) func() []ProgressEvent {
+	t.Helper()
+
+	var mutex sync.Mutex
+	var events []ProgressEvent
+	SetProgress(ProgressFunc(func(event ProgressEvent) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+	}))
+	t.Cleanup(func() { SetProgress(nil) })
+	return func() []ProgressEvent {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]ProgressEvent{}, events...)
+	}
+}
+
+func TestInstallReportsProgress(t *testing.T) {
+	events := recordProgress(t)
+	release := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	useLocalRelease(t, release)
+
+	require.NoError(t, Install(t.TempDir(), release.tool))
+
+	reported := events()
+	require.GreaterOrEqual(t, len(reported), 2)
+	size := int64(len(release.assets[1]))
+	first, last := reported[0], reported[len(reported)-1]
+	require.Equal(t, ProgressEvent{Tool: "dnsx", Asset: first.Asset, Total: size}, first)
+	require.Contains(t, first.Asset, "dnsx_1.1.1_")
+	require.True(t, last.Done)
+	require.NoError(t, last.Err)
+	require.Equal(t, size, last.Current)
+	require.Equal(t, size, last.Total)
+	for _, event := range reported[:len(reported)-1] {
+		require.False(t, event.Done)
+	}
+}
+
+func TestInstallReportsFailedDownload(t *testing.T) {
+	events := recordProgress(t)
+	release := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	release.assets[1] = tarGzArchive(t, "dnsx", "tampered")
+	useLocalRelease(t, release)
+
+	err := Install(t.TempDir(), release.tool)
+	require.Error(t, err)
+
+	reported := events()
+	last := reported[len(reported)-1]
+	require.True(t, last.Done)
+	var mismatchErr *types.ChecksumMismatchError
+	require.True(t, errors.As(last.Err, &mismatchErr))
+}
@@ -0,0 +1,191 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// maxRateLimitWait is the longest a GitHub api request waits for the rate
+// limit to reset. Requests hitting a later reset fail with the rate limit
+// error of GitHub.
+var maxRateLimitWait = time.Minute
+
+// rateLimit tracks the GitHub api rate limit of a host from the
+// X-RateLimit headers of its responses
+type rateLimit struct {
+	mutex     sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+}
+
+// rateLimits holds the rate limit of every host requested, as each GitHub
+// instance limits requests on its own. It is shared by all clients, as
+// GithubClient returns a new client every call.
+type rateLimits struct {
+	mutex sync.Mutex
+	hosts map[string]*rateLimit
+}
+
+var githubRateLimits = &rateLimits{}
+
+// host returns the rate limit of host, host:port for non default ports
+func (r *rateLimits) host(host string) *rateLimit {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.hosts == nil {
+		r.hosts = make(map[string]*rateLimit)
+	}
+	limit, ok := r.hosts[host]
+	if !ok {
+		limit = &rateLimit{}
+		r.hosts[host] = limit
+	}
+	return limit
+}
+
+// update records the rate limit reported by resp
+func (r *rateLimit) update(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.known, r.remaining, r.reset = true, remaining, time.Unix(reset, 0)
+}
+
+// exhausted reports whether no request is left until the rate limit resets
+// and returns the time left until then
+func (r *rateLimit) exhausted() (bool, time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.known || r.remaining > 0 {
+		return false, 0
+	}
+	wait := time.Until(r.reset)
+	if wait <= 0 {
+		return false, 0
+	}
+	return true, wait
+}
+
+// rateLimitTransport delays GitHub api requests while the rate limit is
+// exhausted and resets shortly, and retries requests rejected by a short
+// secondary rate limit once
+type rateLimitTransport struct {
+	next       http.RoundTripper
+	rateLimits *rateLimits
+	// timeout bounds every attempt, a client timeout would include the waits
+	timeout time.Duration
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := t.rateLimits.host(req.URL.Host)
+	if exhausted, wait := limit.exhausted(); exhausted && wait <= maxRateLimitWait {
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := t.send(req)
+	if err != nil {
+		return nil, err
+	}
+	limit.update(resp)
+
+	wait, limited := rateLimitWait(resp)
+	if !limited || wait > maxRateLimitWait || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	if err := sleepContext(req.Context(), wait); err != nil {
+		return nil, err
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	resp, err = t.send(req)
+	if err != nil {
+		return nil, err
+	}
+	limit.update(resp)
+	return resp, nil
+}
+
+// send sends a single attempt of req bounded by the timeout
+func (t *rateLimitTransport) send(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the context of a request once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// rateLimitWait returns the time to wait before retrying a request that was
+// rejected by the primary or a secondary rate limit
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		return retryAfter, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(time.Unix(reset, 0)), 0), true
+}
+
+// githubError adds a hint on raising the rate limit to GitHub rate limit
+// errors of unauthenticated requests
+func githubError(err error) error {
+	if !isRateLimited(err) || githubToken(githubSettings().host) != "" {
+		return err
+	}
+	return fmt.Errorf("%w, set GITHUB_TOKEN or GH_TOKEN or run gh auth login for a higher rate limit", err)
+}
+
+// isRateLimited reports whether err is a primary or secondary GitHub rate limit error
+func isRateLimited(err error) bool {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	return errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr)
+}
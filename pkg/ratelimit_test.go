@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// useRateLimit replaces the shared rate limit state for the test
+func useRateLimit(t *testing.T, wait time.Duration) {
+	t.Helper()
+
+	previousLimits, previousWait := githubRateLimits, maxRateLimitWait
+	githubRateLimits, maxRateLimitWait = &rateLimits{}, wait
+	t.Cleanup(func() {
+		githubRateLimits, maxRateLimitWait = previousLimits, previousWait
+	})
+}
+
+// rateLimitedServer rejects the first request with the given headers
+func rateLimitedServer(t *testing.T, headers map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			for key, value := range headers {
+				w.Header().Set(key, value)
+			}
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "59")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		_, _ = io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func rateLimitedGet(t *testing.T, url string) *http.Response {
+	t.Helper()
+
+	client := &http.Client{Transport: &rateLimitTransport{next: http.DefaultTransport, rateLimits: githubRateLimits, timeout: 5 * time.Second}}
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// serverRateLimit returns the rate limit recorded for server
+func serverRateLimit(t *testing.T, server *httptest.Server) *rateLimit {
+	t.Helper()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return githubRateLimits.host(u.Host)
+}
+
+func TestRateLimitWaitsForShortReset(t *testing.T) {
+	useRateLimit(t, time.Minute)
+	server, requests := rateLimitedServer(t, map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(time.Second).Unix(), 10),
+	})
+
+	resp := rateLimitedGet(t, server.URL)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(2), requests.Load())
+
+	exhausted, _ := serverRateLimit(t, server).exhausted()
+	require.False(t, exhausted)
+}
+
+func TestRateLimitFailsOnLongReset(t *testing.T) {
+	useRateLimit(t, time.Minute)
+	server, requests := rateLimitedServer(t, map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+	})
+
+	resp := rateLimitedGet(t, server.URL)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Equal(t, int32(1), requests.Load())
+
+	exhausted, wait := serverRateLimit(t, server).exhausted()
+	require.True(t, exhausted)
+	require.Greater(t, wait, 59*time.Minute)
+}
+
+func TestRateLimitRetriesSecondaryLimit(t *testing.T) {
+	useRateLimit(t, time.Minute)
+	server, requests := rateLimitedServer(t, map[string]string{"Retry-After": "0"})
+
+	resp := rateLimitedGet(t, server.URL)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(2), requests.Load())
+}
+
+func TestRateLimitIgnoresOtherErrors(t *testing.T) {
+	useRateLimit(t, time.Minute)
+	server, requests := rateLimitedServer(t, map[string]string{"X-RateLimit-Remaining": "42"})
+
+	resp := rateLimitedGet(t, server.URL)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Equal(t, int32(1), requests.Load())
+}
+
+func TestRateLimitPerHost(t *testing.T) {
+	useRateLimit(t, time.Minute)
+	limited, _ := rateLimitedServer(t, map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+	})
+	other, requests := rateLimitedServer(t, map[string]string{"Retry-After": "0"})
+
+	resp := rateLimitedGet(t, limited.URL)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// the exhausted limit of one host neither delays nor is reset by another
+	resp = rateLimitedGet(t, other.URL)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(2), requests.Load())
+	exhausted, _ := serverRateLimit(t, other).exhausted()
+	require.False(t, exhausted)
+	exhausted, _ = serverRateLimit(t, limited).exhausted()
+	require.True(t, exhausted)
+}
+
+// usePublicReleases serves release assets under /<owner>/<repo>/releases/download/<tag>/
+func usePublicReleases(t *testing.T, tag, name, content string) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/acme/scanner/releases/download/"+tag+"/"+name {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, content)
+	}))
+	t.Cleanup(server.Close)
+
+	useGithub(t, GithubOptions{DownloadURL: server.URL})
+}
+
+func TestDownloadFallsBackToPublicURL(t *testing.T) {
+	useRateLimit(t, time.Minute)
+	// the rate limit is exhausted for an hour, the api must not be used
+	limit := githubRateLimits.host(githubSettings().apiURL.Host)
+	limit.known, limit.remaining, limit.reset = true, 0, time.Now().Add(time.Hour)
+
+	tool := types.Tool{
+		Name:    "scanner",
+		Owner:   "acme",
+		Repo:    "scanner",
+		Version: "1.0.0",
+		Assets:  map[string]string{"scanner_linux_amd64.tar.gz": "7"},
+	}
+	for _, tag := range []string{"v1.0.0", "1.0.0"} {
+		usePublicReleases(t, tag, "scanner_linux_amd64.tar.gz", "archive")
+
+		rc, _, err := downloadAsset(tool, 7)
+		require.NoError(t, err, tag)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		require.Equal(t, "archive", string(data))
+	}
+
+	_, _, err := downloadAsset(tool, 8)
+	require.Error(t, err)
+}
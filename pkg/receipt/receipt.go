@@ -0,0 +1,220 @@
+// Package receipt persists what pdtm installed for each tool, so the
+// installed version is known without running the binary and binaries
+// changed outside of pdtm are detected
+package receipt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// Receipt describes the installation of a tool
+type Receipt struct {
+	Tool        string            `json:"tool"`
+	Version     string            `json:"version"`
+	InstallType types.InstallType `json:"install_type"`
+	// Path is the binary path the tool is installed in
+	Path string `json:"path"`
+	// Asset is the release asset the binary was extracted from, empty for go installs
+	Asset string `json:"asset,omitempty"`
+	// AssetChecksum is the sha256 digest of Asset
+	AssetChecksum string `json:"asset_checksum,omitempty"`
+	BinaryPath    string `json:"binary_path"`
+	// BinaryChecksum, BinarySize and BinaryModTime describe the installed
+	// binary for drift detection
+	BinaryChecksum string    `json:"binary_checksum"`
+	BinarySize     int64     `json:"binary_size"`
+	BinaryModTime  time.Time `json:"binary_mod_time"`
+	// Files lists the auxiliary files installed with the binary
+	Files       []string  `json:"files,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// Drift describes how the binary on disk differs from its receipt
+type Drift string
+
+const (
+	// NoDrift is reported when the binary matches its receipt
+	NoDrift Drift = ""
+	// Missing is reported when the binary was removed outside of pdtm
+	Missing Drift = "missing"
+	// Modified is reported when the binary was replaced outside of pdtm
+	Modified Drift = "modified"
+)
+
+// New returns the receipt of tool installed in path as the binary at binaryPath
+func New(tool types.Tool, installType types.InstallType, path, binaryPath string) (Receipt, error) {
+	receipt := Receipt{
+		Tool:        tool.Name,
+		Version:     strings.TrimPrefix(tool.Version, "v"),
+		InstallType: installType,
+		Path:        path,
+		BinaryPath:  binaryPath,
+		InstalledAt: time.Now().UTC(),
+	}
+	info, err := os.Stat(binaryPath)
+	if err != nil {
+		return receipt, err
+	}
+	checksum, err := FileChecksum(binaryPath)
+	if err != nil {
+		return receipt, err
+	}
+	receipt.BinaryChecksum, receipt.BinarySize, receipt.BinaryModTime = checksum, info.Size(), info.ModTime().UTC()
+	return receipt, nil
+}
+
+// Drift compares the binary on disk with the receipt. The binary is only
+// hashed when its modification time changed.
+func (r Receipt) Drift() (Drift, error) {
+	info, err := os.Stat(r.BinaryPath)
+	if os.IsNotExist(err) {
+		return Missing, nil
+	}
+	if err != nil {
+		return NoDrift, err
+	}
+	if info.Size() != r.BinarySize {
+		return Modified, nil
+	}
+	if info.ModTime().Equal(r.BinaryModTime) {
+		return NoDrift, nil
+	}
+	checksum, err := FileChecksum(r.BinaryPath)
+	if err != nil {
+		return NoDrift, err
+	}
+	if checksum != r.BinaryChecksum {
+		return Modified, nil
+	}
+	return NoDrift, nil
+}
+
+// FileChecksum returns the hex sha256 digest of file
+func FileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Store keeps the receipts of all binary paths in a single file. It is
+// safe for concurrent use.
+type Store struct {
+	file     string
+	mutex    sync.Mutex
+	receipts map[string]Receipt
+}
+
+// Open reads the receipts stored in file, a missing file yields no receipts
+func Open(file string) (*Store, error) {
+	store := &Store{file: file, receipts: make(map[string]Receipt)}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var receipts []Receipt
+	if err := json.Unmarshal(data, &receipts); err != nil {
+		return nil, err
+	}
+	for _, receipt := range receipts {
+		store.receipts[receipt.key()] = receipt
+	}
+	return store, nil
+}
+
+// key identifies the receipt of toolName installed in binPath
+func key(binPath, toolName string) string {
+	return filepath.Join(filepath.Clean(binPath), strings.ToLower(toolName))
+}
+
+func (r Receipt) key() string {
+	if r.Path == "" {
+		// receipts without a path are keyed by the directory of the binary
+		return key(filepath.Dir(r.BinaryPath), r.Tool)
+	}
+	return key(r.Path, r.Tool)
+}
+
+// Get returns the receipt of toolName installed in binPath
+func (s *Store) Get(binPath, toolName string) (Receipt, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	receipt, ok := s.receipts[key(binPath, toolName)]
+	return receipt, ok
+}
+
+// Put records receipt, replacing the previous receipt of the tool
+func (s *Store) Put(receipt Receipt) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.receipts[receipt.key()] = receipt
+	return s.save()
+}
+
+// Delete removes the receipt of toolName installed in binPath
+func (s *Store) Delete(binPath, toolName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	k := key(binPath, toolName)
+	if _, ok := s.receipts[k]; !ok {
+		return nil
+	}
+	delete(s.receipts, k)
+	return s.save()
+}
+
+// List returns all receipts ordered by binary path
+func (s *Store) List() []Receipt {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.list()
+}
+
+func (s *Store) list() []Receipt {
+	receipts := make([]Receipt, 0, len(s.receipts))
+	for _, receipt := range s.receipts {
+		receipts = append(receipts, receipt)
+	}
+	sort.Slice(receipts, func(i, j int) bool {
+		return receipts[i].BinaryPath < receipts[j].BinaryPath
+	})
+	return receipts
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.file), os.ModePerm); err != nil {
+		return err
+	}
+	// write and rename so an interrupted save never truncates the receipts
+	tmpFile := s.file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, s.file)
+}
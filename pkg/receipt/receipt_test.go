@@ -0,0 +1,99 @@
+package receipt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBinary(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	binaryPath := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(binaryPath, []byte(content), 0755))
+	return binaryPath
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "pdtm", "receipts.json")
+	binPath := filepath.Join(dir, "bin")
+	require.NoError(t, os.MkdirAll(binPath, os.ModePerm))
+
+	store, err := Open(file)
+	require.NoError(t, err)
+	_, ok := store.Get(binPath, "dnsx")
+	require.False(t, ok)
+
+	// the binary of tools installed side by side is below the binary path
+	versionDir := filepath.Join(binPath, "versions", "dnsx", "1.1.1")
+	require.NoError(t, os.MkdirAll(versionDir, os.ModePerm))
+	r, err := New(types.Tool{Name: "dnsx", Version: "v1.1.1"}, types.Binary, binPath, writeBinary(t, versionDir, "dnsx", "binary"))
+	require.NoError(t, err)
+	r.Asset, r.Files = "dnsx_1.1.1_linux_amd64.zip", []string{"/share/man/man1/dnsx.1"}
+	require.NoError(t, store.Put(r))
+
+	// receipts are kept per binary path
+	otherPath := filepath.Join(dir, "other")
+	require.NoError(t, os.MkdirAll(otherPath, os.ModePerm))
+	other, err := New(types.Tool{Name: "dnsx", Version: "1.0.0"}, types.Go, otherPath, writeBinary(t, otherPath, "dnsx", "other"))
+	require.NoError(t, err)
+	require.NoError(t, store.Put(other))
+
+	reopened, err := Open(file)
+	require.NoError(t, err)
+	got, ok := reopened.Get(binPath, "DNSX")
+	require.True(t, ok)
+	require.Equal(t, "1.1.1", got.Version)
+	require.Equal(t, types.Binary, got.InstallType)
+	require.Equal(t, r.Files, got.Files)
+	require.Len(t, reopened.List(), 2)
+
+	require.NoError(t, reopened.Delete(binPath, "dnsx"))
+	require.NoError(t, reopened.Delete(binPath, "dnsx"))
+	reopened, err = Open(file)
+	require.NoError(t, err)
+	_, ok = reopened.Get(binPath, "dnsx")
+	require.False(t, ok)
+	got, ok = reopened.Get(otherPath, "dnsx")
+	require.True(t, ok)
+	require.Equal(t, types.Go, got.InstallType)
+}
+
+func TestDrift(t *testing.T) {
+	dir := t.TempDir()
+	binaryPath := writeBinary(t, dir, "dnsx", "binary")
+	r, err := New(types.Tool{Name: "dnsx", Version: "1.1.1"}, types.Binary, dir, binaryPath)
+	require.NoError(t, err)
+
+	drift, err := r.Drift()
+	require.NoError(t, err)
+	require.Equal(t, NoDrift, drift)
+
+	// touching the binary without changing it is no drift
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(binaryPath, later, later))
+	drift, err = r.Drift()
+	require.NoError(t, err)
+	require.Equal(t, NoDrift, drift)
+
+	// same size, different content
+	writeBinary(t, dir, "dnsx", "BINARY")
+	drift, err = r.Drift()
+	require.NoError(t, err)
+	require.Equal(t, Modified, drift)
+
+	writeBinary(t, dir, "dnsx", "a different binary")
+	drift, err = r.Drift()
+	require.NoError(t, err)
+	require.Equal(t, Modified, drift)
+
+	require.NoError(t, os.Remove(binaryPath))
+	drift, err = r.Drift()
+	require.NoError(t, err)
+	require.Equal(t, Missing, drift)
+}
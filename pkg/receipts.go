@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/receipt"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/version"
+)
+
+var (
+	receipts      *receipt.Store
+	receiptsMutex sync.RWMutex
+)
+
+// SetReceipts sets the store installs are recorded in, nil disables receipts
+func SetReceipts(store *receipt.Store) {
+	receiptsMutex.Lock()
+	defer receiptsMutex.Unlock()
+	receipts = store
+}
+
+func currentReceipts() *receipt.Store {
+	receiptsMutex.RLock()
+	defer receiptsMutex.RUnlock()
+	return receipts
+}
+
+// recordReceipt records the installation of tool in path. The tool is
+// installed at this point, so failures are only reported.
+func recordReceipt(path string, tool types.Tool, installType types.InstallType, assetName, assetChecksum string, files []string) {
+	store := currentReceipts()
+	if store == nil {
+		return
+	}
+	binaryPath, exists := ospath.GetExecutablePath(path, tool.Name)
+	if !exists {
+		return
+	}
+	installed := versionInstall{InstallType: installType, Asset: assetName, AssetChecksum: assetChecksum}
+	if err := writeVersionInstall(path, tool.Name, tool.Version, installed); err != nil {
+		gologger.Verbose().Msgf("%s: could not record install of %s: %s", tool.Name, tool.Version, err)
+	}
+	r, err := receipt.New(tool, installType, path, binaryPath)
+	if err == nil {
+		r.Asset, r.AssetChecksum, r.Files = assetName, assetChecksum, files
+		err = store.Put(r)
+	}
+	if err != nil {
+		gologger.Warning().Msgf("%s: could not record install receipt: %s", tool.Name, err)
+	}
+}
+
+// forgetReceipt removes the receipt of toolName installed in path
+func forgetReceipt(path, toolName string) {
+	store := currentReceipts()
+	if store == nil {
+		return
+	}
+	if err := store.Delete(path, toolName); err != nil {
+		gologger.Warning().Msgf("%s: could not remove install receipt: %s", toolName, err)
+	}
+}
+
+// recordedFiles returns the auxiliary files in the receipt of toolName
+// installed in path
+func recordedFiles(path, toolName string) []string {
+	store := currentReceipts()
+	if store == nil {
+		return nil
+	}
+	r, _ := store.Get(path, toolName)
+	return r.Files
+}
+
+// InstalledReceipt returns the receipt of toolName installed in path and
+// how the binary on disk drifted from it
+func InstalledReceipt(path, toolName string) (receipt.Receipt, receipt.Drift, bool) {
+	store := currentReceipts()
+	if store == nil {
+		return receipt.Receipt{}, receipt.NoDrift, false
+	}
+	r, ok := store.Get(path, toolName)
+	if !ok {
+		return r, receipt.NoDrift, false
+	}
+	drift, err := r.Drift()
+	if err != nil {
+		gologger.Verbose().Msgf("%s: could not check %s against its receipt: %s", toolName, r.BinaryPath, err)
+		drift = receipt.Modified
+	}
+	return r, drift, true
+}
+
+// InstalledVersion returns the version of tool installed in path. It is read
+// from the install receipt while the binary matches it, and from the output
+// of the binary otherwise.
+func InstalledVersion(path string, tool types.Tool) (string, error) {
+	r, drift, ok := InstalledReceipt(path, tool.Name)
+	if ok && drift == receipt.NoDrift {
+		return r.Version, nil
+	}
+	if ok {
+		gologger.Verbose().Msgf("%s: binary is %s since it was installed, running it for its version", tool.Name, drift)
+	}
+	return version.ExtractInstalledVersion(tool, path)
+}
@@ -0,0 +1,139 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/receipt"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// useReceipts records receipts in a temporary store for the test
+func useReceipts(t *testing.T) *receipt.Store {
+	t.Helper()
+
+	store, err := receipt.Open(filepath.Join(t.TempDir(), "receipts.json"))
+	require.NoError(t, err)
+	SetReceipts(store)
+	t.Cleanup(func() { SetReceipts(nil) })
+	return store
+}
+
+func TestInstallReceipt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version scripts need a shell")
+	}
+	store := useReceipts(t)
+	pathBin := t.TempDir()
+	release := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	useLocalRelease(t, release)
+
+	require.NoError(t, Install(pathBin, release.tool))
+	r, ok := store.Get(pathBin, "dnsx")
+	require.True(t, ok)
+	require.Equal(t, "1.1.1", r.Version)
+	require.Equal(t, types.Binary, r.InstallType)
+	archiveName := "dnsx_1.1.1_" + ospath.CheckOS() + ".tar.gz"
+	require.Equal(t, archiveName, r.Asset)
+	sum := sha256.Sum256(release.assets[1])
+	require.Equal(t, hex.EncodeToString(sum[:]), r.AssetChecksum)
+
+	// the receipt answers while the binary matches it
+	r.Version = "1.1.0"
+	require.NoError(t, store.Put(r))
+	installedVersion, err := InstalledVersion(pathBin, release.tool)
+	require.NoError(t, err)
+	require.Equal(t, "1.1.0", installedVersion)
+
+	// a binary replaced outside of pdtm is asked for its version
+	require.NoError(t, os.WriteFile(r.BinaryPath, []byte(versionScript("2.0.0")), 0755))
+	_, drift, ok := InstalledReceipt(pathBin, "dnsx")
+	require.True(t, ok)
+	require.Equal(t, receipt.Modified, drift)
+	installedVersion, err = InstalledVersion(pathBin, release.tool)
+	require.NoError(t, err)
+	require.Equal(t, "2.0.0", installedVersion)
+
+	require.NoError(t, Remove(pathBin, release.tool))
+	_, ok = store.Get(pathBin, "dnsx")
+	require.False(t, ok)
+}
+
+func TestUpdateReadsReceipt(t *testing.T) {
+	store := useReceipts(t)
+	pathBin := t.TempDir()
+	installed := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	useLocalRelease(t, installed)
+	require.NoError(t, Install(pathBin, installed.tool))
+
+	release := newLocalRelease(t, "dnsx", "1.1.2", versionScript("1.1.2"))
+	useLocalRelease(t, release)
+	require.NoError(t, Update(pathBin, release.tool, true))
+	r, ok := store.Get(pathBin, "dnsx")
+	require.True(t, ok)
+	require.Equal(t, "1.1.2", r.Version)
+	require.ErrorIs(t, Update(pathBin, release.tool, true), types.ErrIsUpToDate)
+}
+
+func TestUpdateKeepsGoInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go needs a shell")
+	}
+	store := useReceipts(t)
+	pathBin := t.TempDir()
+	installed := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	useLocalRelease(t, installed)
+	require.NoError(t, Install(pathBin, installed.tool))
+	r, ok := store.Get(pathBin, "dnsx")
+	require.True(t, ok)
+	r.InstallType = types.Go
+	require.NoError(t, store.Put(r))
+
+	// go install writes a binary reporting the requested module version
+	goBin := t.TempDir()
+	fakeGo := "#!/bin/sh\nprintf '#!/bin/sh\\necho %s\\n' \"${3##*@}\" > \"$GOBIN/dnsx\"\nchmod +x \"$GOBIN/dnsx\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(goBin, "go"), []byte(fakeGo), 0755))
+	t.Setenv("PATH", goBin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// a release without assets proves no release asset replaces the go build
+	release := newLocalRelease(t, "dnsx", "1.1.2", versionScript("1.1.2"))
+	release.tool.Assets = nil
+	require.NoError(t, Update(pathBin, release.tool, true))
+	require.Equal(t, versionScript("1.1.2"), readBinary(t, pathBin, "dnsx"))
+	r, ok = store.Get(pathBin, "dnsx")
+	require.True(t, ok)
+	require.Equal(t, types.Go, r.InstallType)
+	require.Equal(t, "1.1.2", r.Version)
+}
+
+func TestRemoveMissingBinary(t *testing.T) {
+	store := useReceipts(t)
+	root := t.TempDir()
+	pathBin := filepath.Join(root, "bin")
+	useConfigDir(t, filepath.Join(root, "config"))
+	release := releaseWithFiles(t, "2.6.0", map[string]string{"docs/subfinder.1": ".TH SUBFINDER 1"})
+	useLocalRelease(t, release)
+	require.NoError(t, Install(pathBin, release.tool))
+	r, ok := store.Get(pathBin, "subfinder")
+	require.True(t, ok)
+	require.Len(t, r.Files, 1)
+
+	// the binary deleted outside of pdtm leaves the receipt, versions and files
+	entryPath, exists := ospath.GetEntryPath(pathBin, "subfinder")
+	require.True(t, exists)
+	require.NoError(t, os.Remove(entryPath))
+	require.NoError(t, os.Remove(r.BinaryPath))
+
+	require.NoError(t, Remove(pathBin, release.tool))
+	_, ok = store.Get(pathBin, "subfinder")
+	require.False(t, ok)
+	require.NoFileExists(t, r.Files[0])
+	require.NoDirExists(t, ospath.ToolVersionsDir(pathBin, "subfinder"))
+	require.Error(t, Remove(pathBin, release.tool))
+}
@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// GetRelease returns a copy of tool describing the release tagged with
+// version, with the asset list fetched from the GitHub releases API
+func GetRelease(tool types.Tool, version string) (types.Tool, error) {
+	rel, resp, err := GithubClient().Repositories.GetReleaseByTag(context.Background(), tool.GetOwner(), tool.Repo, releaseTag(version))
+	if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+		// some projects tag their releases without the v prefix
+		rel, _, err = GithubClient().Repositories.GetReleaseByTag(context.Background(), tool.GetOwner(), tool.Repo, strings.TrimPrefix(version, "v"))
+	}
+	if err != nil {
+		return tool, githubError(err)
+	}
+	return releaseTool(tool, rel), nil
+}
+
+// GetLatestRelease returns a copy of tool describing the latest release of
+// its repository
+func GetLatestRelease(tool types.Tool) (types.Tool, error) {
+	rel, _, err := GithubClient().Repositories.GetLatestRelease(context.Background(), tool.GetOwner(), tool.Repo)
+	if err != nil {
+		return tool, githubError(err)
+	}
+	return releaseTool(tool, rel), nil
+}
+
+// ListReleases returns a copy of tool for every published release of its
+// repository, newest first
+func ListReleases(tool types.Tool) ([]types.Tool, error) {
+	var releases []types.Tool
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		rels, resp, err := GithubClient().Repositories.ListReleases(context.Background(), tool.GetOwner(), tool.Repo, opt)
+		if err != nil {
+			return nil, githubError(err)
+		}
+		for _, rel := range rels {
+			if rel.GetDraft() {
+				continue
+			}
+			releases = append(releases, releaseTool(tool, rel))
+		}
+		if resp.NextPage == 0 {
+			return releases, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// releaseTool returns a copy of tool with the version and assets of rel. The
+// assets are downloaded from GitHub, a download url such as the one of a
+// pdtm mirror only serves the release tool was listed with.
+func releaseTool(tool types.Tool, rel *github.RepositoryRelease) types.Tool {
+	tool.Version = strings.TrimPrefix(rel.GetTagName(), "v")
+	tool.Assets = make(map[string]string, len(rel.Assets))
+	tool.Checksums = nil
+	tool.DownloadURL = ""
+	for _, asset := range rel.Assets {
+		tool.Assets[asset.GetName()] = strconv.FormatInt(asset.GetID(), 10)
+	}
+	return tool
+}
+
+// releaseTag returns the git tag used by projectdiscovery releases for version
+func releaseTag(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
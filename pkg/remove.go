@@ -5,22 +5,41 @@ import (
 	"os"
 
 	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/receipt"
 	"github.com/projectdiscovery/pdtm/pkg/types"
 
 	"github.com/projectdiscovery/gologger"
 )
 
-// Remove removes given tool
+// Remove removes given tool with the binary and auxiliary files recorded in
+// its receipt. A tool whose binary was deleted outside of pdtm is still
+// cleaned up from its receipt.
 func Remove(path string, tool types.Tool) error {
-	executablePath, exists := ospath.GetExecutablePath(path, tool.Name)
+	r, drift, hasReceipt := InstalledReceipt(path, tool.Name)
+	// the shim of tools installed side by side or the binary itself
+	entryPath, exists := ospath.GetEntryPath(path, tool.Name)
+	if !exists && !hasReceipt {
+		return fmt.Errorf(types.ErrToolNotFound, tool.Name, entryPath)
+	}
+	gologger.Info().Msgf("removing %s...", tool.Name)
+	if drift == receipt.Missing {
+		gologger.Warning().Msgf("%s: binary %s was removed outside of pdtm", tool.Name, r.BinaryPath)
+	}
 	if exists {
-		gologger.Info().Msgf("removing %s...", tool.Name)
-		err := os.Remove(executablePath)
-		if err != nil {
+		if err := os.Remove(entryPath); err != nil {
+			return err
+		}
+	}
+	if hasReceipt && r.BinaryPath != entryPath {
+		if err := os.Remove(r.BinaryPath); err != nil && !os.IsNotExist(err) {
 			return err
 		}
-		gologger.Info().Msgf("removed %s", tool.Name)
-		return nil
 	}
-	return fmt.Errorf(types.ErrToolNotFound, tool.Name, executablePath)
+	if err := removeVersions(path, tool.Name); err != nil {
+		return err
+	}
+	removeFiles(tool.Name, r.Files)
+	forgetReceipt(path, tool.Name)
+	gologger.Info().Msgf("removed %s", tool.Name)
+	return nil
 }
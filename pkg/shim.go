@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	osutils "github.com/projectdiscovery/utils/os"
+)
+
+// LocalVersionFile selects tool versions for a directory and its
+// subdirectories, one "<tool> <version>" per line
+const LocalVersionFile = ".pdtm-version"
+
+// unixShim runs the version of a tool named in the nearest .pdtm-version
+// file, or its active version
+const unixShim = `#!/bin/sh
+# pdtm shim for %[1]s: runs the version named in the nearest %[4]s file
+# or the active version selected with pdtm -use. Generated by pdtm.
+versions=%[2]s
+version=
+dir=${PWD:-/}
+while :; do
+	if [ -f "$dir/%[4]s" ]; then
+		version=$(sed -n 's/^[[:space:]]*%[3]s[[:space:]]\{1,\}v\{0,1\}\([^[:space:]#]\{1,\}\).*/\1/p' "$dir/%[4]s" | head -n 1)
+		[ -n "$version" ] && break
+	fi
+	[ "$dir" = / ] && break
+	dir=${dir%%/*}
+	dir=${dir:-/}
+done
+if [ -z "$version" ]; then
+	read -r version < "$versions/%[5]s"
+fi
+if [ ! -x "$versions/$version/%[1]s" ]; then
+	echo "pdtm: %[1]s $version is not installed, run pdtm -use %[1]s@$version" >&2
+	exit 127
+fi
+exec "$versions/$version/%[1]s" "$@"
+`
+
+// windowsShim runs the version of a tool named in the nearest .pdtm-version
+// file, or its active version. It is written with CRLF line endings.
+const windowsShim = `@echo off
+rem pdtm shim for %[1]s: runs the version named in the nearest %[3]s file
+rem or the active version selected with pdtm -use. Generated by pdtm.
+setlocal
+set "versions=%[2]s"
+set "version="
+set "dir=%%CD%%"
+:search
+if exist "%%dir%%\%[3]s" (
+	for /f "usebackq eol=# tokens=1,2" %%%%a in ("%%dir%%\%[3]s") do (
+		if not defined version if "%%%%a"=="%[1]s" set "version=%%%%b"
+	)
+)
+if defined version goto run
+for %%%%d in ("%%dir%%\..") do set "parent=%%%%~fd"
+if "%%parent%%"=="%%dir%%" goto active
+set "dir=%%parent%%"
+goto search
+:active
+set /p version=<"%%versions%%\%[4]s"
+:run
+if defined version if "%%version:~0,1%%"=="v" set "version=%%version:~1%%"
+if not exist "%%versions%%\%%version%%\%[1]s.exe" (
+	echo pdtm: %[1]s %%version%% is not installed, run pdtm -use %[1]s@%%version%% 1>&2
+	exit /b 127
+)
+"%%versions%%\%%version%%\%[1]s.exe" %%*
+`
+
+// writeShim writes the shim of toolName to path, replacing the binary or
+// shim there atomically
+func writeShim(path, toolName string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	versionsDir := ospath.ToolVersionsDir(absPath, toolName)
+
+	shimPath, content := filepath.Join(path, toolName), ""
+	if osutils.IsWindows() {
+		shimPath += ".bat"
+		content = strings.ReplaceAll(fmt.Sprintf(windowsShim, toolName, versionsDir, LocalVersionFile, ospath.ActiveFile), "\n", "\r\n")
+	} else {
+		content = fmt.Sprintf(unixShim, toolName, shellQuote(versionsDir), sedPattern(toolName), LocalVersionFile, ospath.ActiveFile)
+	}
+	// a binary installed before versions were kept side by side may use
+	// another extension than the shim
+	if entryPath, exists := ospath.GetEntryPath(path, toolName); exists && entryPath != shimPath {
+		if err := os.Remove(entryPath); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(shimPath, []byte(content), 0755)
+}
+
+// writeFileAtomic writes data to a temporary file next to file and renames
+// it into place
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	tmpFile := file + tmpSuffix
+	if err := os.WriteFile(tmpFile, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, file); err != nil {
+		_ = os.Remove(tmpFile)
+		return err
+	}
+	return nil
+}
+
+// shellQuote quotes value for a posix shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// sedPattern escapes toolName for a sed basic regular expression within
+// single quotes
+func sedPattern(toolName string) string {
+	return strings.NewReplacer(`\`, `\\`, `.`, `\.`, `[`, `\[`, `*`, `\*`, `^`, `\^`, `$`, `\$`, `/`, `\/`, `'`, `'\''`).Replace(toolName)
+}
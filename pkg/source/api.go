@@ -0,0 +1,26 @@
+package source
+
+import (
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/utils"
+)
+
+// API lists tools from a pdtm api server
+type API struct {
+	// Host is the api base url, PDTM_SERVER or api.pdtm.sh when empty
+	Host string
+}
+
+func (a *API) Name() string {
+	if a.Host == "" {
+		return "api"
+	}
+	return "api:" + a.Host
+}
+
+func (a *API) Tools() ([]types.Tool, error) {
+	if a.Host == "" {
+		return utils.FetchToolList()
+	}
+	return utils.FetchToolListFrom(a.Host)
+}
@@ -0,0 +1,90 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// File lists tools from a local YAML or JSON registry file. The file holds
+// either a list of tools, in the format served by the pdtm api, or a
+// document with a tools key. Tools without a version are resolved to the
+// latest release of their repository, tools whose release can not be
+// fetched are skipped and reported in the returned error.
+type File struct {
+	Path string
+	// Releases caches the latest releases resolved for tools without a
+	// version, they are resolved on every call when nil
+	Releases *ReleaseCache
+}
+
+// registry is the document form of a registry file
+type registry struct {
+	Tools []types.Tool `yaml:"tools"`
+}
+
+func (f *File) Name() string {
+	return "file:" + f.Path
+}
+
+func (f *File) Tools() ([]types.Tool, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON so a single decoder handles both formats
+	var tools []types.Tool
+	if err := fileutil.UnmarshalFromReader(fileutil.YAML, bytes.NewReader(data), &tools); err != nil {
+		var doc registry
+		if err := fileutil.UnmarshalFromReader(fileutil.YAML, bytes.NewReader(data), &doc); err != nil {
+			return nil, err
+		}
+		tools = doc.Tools
+	}
+
+	for i, tool := range tools {
+		if tool.Name == "" {
+			return nil, fmt.Errorf("tool #%d without name", i+1)
+		}
+	}
+
+	releases := f.Releases.load()
+	var resolvedReleases bool
+	resolved := make([]types.Tool, 0, len(tools))
+	var errs []error
+	for _, tool := range tools {
+		if tool.Repo == "" {
+			tool.Repo = tool.Name
+		}
+		if tool.InstallType == "" {
+			tool.InstallType = types.Binary
+		}
+		if tool.Version == "" {
+			if cached, ok := f.Releases.get(releases, tool); ok {
+				resolved = append(resolved, cached)
+				continue
+			}
+			release, err := pkg.GetLatestRelease(tool)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not fetch latest release of %s/%s: %w", tool.GetOwner(), tool.Repo, err))
+				continue
+			}
+			tool = release
+			f.Releases.put(releases, tool)
+			resolvedReleases = true
+		}
+		resolved = append(resolved, tool)
+	}
+	if resolvedReleases && f.Releases != nil {
+		if err := f.Releases.save(releases); err != nil {
+			errs = append(errs, fmt.Errorf("could not cache releases: %w", err))
+		}
+	}
+	return resolved, errors.Join(errs...)
+}
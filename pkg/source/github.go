@@ -0,0 +1,59 @@
+package source
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/github"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// GitHub lists the latest release of every repository of a GitHub
+// organization. It needs one api request per repository, so a GITHUB_TOKEN
+// is recommended for organizations with many repositories.
+type GitHub struct {
+	Organization string
+}
+
+func (g *GitHub) Name() string {
+	return "github:" + g.Organization
+}
+
+func (g *GitHub) Tools() ([]types.Tool, error) {
+	client := pkg.GithubClient()
+	var tools []types.Tool
+	opt := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		repos, resp, err := client.Repositories.ListByOrg(context.Background(), g.Organization, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if repo.GetArchived() || repo.GetFork() {
+				continue
+			}
+			tool, err := pkg.GetLatestRelease(types.Tool{
+				Name:        repo.GetName(),
+				Owner:       g.Organization,
+				Repo:        repo.GetName(),
+				InstallType: types.Binary,
+			})
+			if err != nil {
+				// repositories without releases have nothing to install
+				if errResp, ok := err.(*github.ErrorResponse); ok && errResp.Response.StatusCode == http.StatusNotFound {
+					continue
+				}
+				return nil, err
+			}
+			if len(tool.Assets) == 0 {
+				continue
+			}
+			tools = append(tools, tool)
+		}
+		if resp.NextPage == 0 {
+			return tools, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
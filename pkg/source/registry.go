@@ -0,0 +1,110 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// entry is a tool recorded in a registry file. Only the repository and how
+// to install it are recorded, the File source resolves the latest release.
+type entry struct {
+	Name           string              `yaml:"name"`
+	Owner          string              `yaml:"owner,omitempty"`
+	Repo           string              `yaml:"repo"`
+	InstallType    types.InstallType   `yaml:"install_type,omitempty"`
+	GoInstallPath  string              `yaml:"go_install_path,omitempty"`
+	AssetTemplate  string              `yaml:"asset_template,omitempty"`
+	OSAliases      map[string][]string `yaml:"os_aliases,omitempty"`
+	ArchAliases    map[string][]string `yaml:"arch_aliases,omitempty"`
+	ArchiveFormat  string              `yaml:"archive_format,omitempty"`
+	VersionCommand string              `yaml:"version_command,omitempty"`
+	Files          []types.ToolFile    `yaml:"files,omitempty"`
+}
+
+type entries struct {
+	Tools []entry `yaml:"tools"`
+}
+
+// Register adds tool to the registry file, replacing a registered tool with
+// the same name. The file is created when missing.
+func Register(file string, tool types.Tool) error {
+	registered, err := loadEntries(file)
+	if err != nil {
+		return err
+	}
+	added := entry{
+		Name:           tool.Name,
+		Owner:          tool.Owner,
+		Repo:           tool.Repo,
+		InstallType:    tool.InstallType,
+		GoInstallPath:  tool.GoInstallPath,
+		AssetTemplate:  tool.AssetTemplate,
+		OSAliases:      tool.OSAliases,
+		ArchAliases:    tool.ArchAliases,
+		ArchiveFormat:  tool.ArchiveFormat,
+		VersionCommand: tool.VersionCommand,
+		Files:          tool.Files,
+	}
+	for i, e := range registered.Tools {
+		if strings.EqualFold(e.Name, tool.Name) {
+			registered.Tools[i] = added
+			return saveEntries(file, registered)
+		}
+	}
+	registered.Tools = append(registered.Tools, added)
+	return saveEntries(file, registered)
+}
+
+// Unregister removes the tool named toolName from the registry file,
+// reporting whether it was registered
+func Unregister(file, toolName string) (bool, error) {
+	registered, err := loadEntries(file)
+	if err != nil {
+		return false, err
+	}
+	for i, e := range registered.Tools {
+		if strings.EqualFold(e.Name, toolName) {
+			registered.Tools = append(registered.Tools[:i], registered.Tools[i+1:]...)
+			return true, saveEntries(file, registered)
+		}
+	}
+	return false, nil
+}
+
+// loadEntries reads the registry file, a missing file yields no entries
+func loadEntries(file string) (*entries, error) {
+	registered := &entries{}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return registered, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if err := fileutil.UnmarshalFromReader(fileutil.YAML, f, registered); err != nil {
+		return nil, err
+	}
+	return registered, nil
+}
+
+func saveEntries(file string, registered *entries) error {
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := fileutil.MarshalToWriter(fileutil.YAML, f, registered); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
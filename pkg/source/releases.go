@@ -0,0 +1,83 @@
+package source
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// ReleaseCache keeps the latest releases resolved for tools without a
+// version in a JSON file, so that listing the tools of a registry file does
+// not query GitHub for every tool on every run
+type ReleaseCache struct {
+	Path string
+	// TTL is how long a resolved release is used before it is resolved again
+	TTL time.Duration
+	// ReadOnly uses the cached releases without saving newly resolved ones
+	ReadOnly bool
+}
+
+// cachedRelease is the release of a repository resolved at ResolvedAt
+type cachedRelease struct {
+	Version    string            `json:"version"`
+	Assets     map[string]string `json:"assets"`
+	ResolvedAt time.Time         `json:"resolved_at"`
+}
+
+func releaseKey(tool types.Tool) string {
+	return strings.ToLower(tool.GetOwner() + "/" + tool.Repo)
+}
+
+// get returns tool with the cached release of its repository when it was
+// resolved within the TTL
+func (c *ReleaseCache) get(releases map[string]cachedRelease, tool types.Tool) (types.Tool, bool) {
+	if c == nil {
+		return tool, false
+	}
+	release, ok := releases[releaseKey(tool)]
+	if !ok || time.Since(release.ResolvedAt) > c.TTL {
+		return tool, false
+	}
+	tool.Version, tool.Assets = release.Version, release.Assets
+	return tool, true
+}
+
+// put records the release resolved for tool
+func (c *ReleaseCache) put(releases map[string]cachedRelease, tool types.Tool) {
+	releases[releaseKey(tool)] = cachedRelease{Version: tool.Version, Assets: tool.Assets, ResolvedAt: time.Now()}
+}
+
+// load reads the cache file, a missing or unreadable file is an empty cache.
+// Without a cache every release is resolved again.
+func (c *ReleaseCache) load() map[string]cachedRelease {
+	releases := make(map[string]cachedRelease)
+	if c == nil {
+		return releases
+	}
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return releases
+	}
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return make(map[string]cachedRelease)
+	}
+	return releases
+}
+
+func (c *ReleaseCache) save(releases map[string]cachedRelease) error {
+	if c.ReadOnly {
+		return nil
+	}
+	data, err := json.Marshal(releases)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path, data, 0644)
+}
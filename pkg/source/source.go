@@ -0,0 +1,70 @@
+// Package source provides the registries pdtm reads its tool list from
+package source
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// ToolSource provides a list of tools pdtm can manage
+type ToolSource interface {
+	// Name identifies the source in logs
+	Name() string
+	// Tools returns the tools provided by the source
+	Tools() ([]types.Tool, error)
+}
+
+// Parse creates a tool source from its specification:
+//
+//	api               the pdtm api set by PDTM_SERVER
+//	api:<url>         a pdtm api served at url
+//	file:<path>       a local YAML or JSON registry file
+//	github            latest releases of the projectdiscovery organization
+//	github:<org>      latest releases of a GitHub organization
+func Parse(spec string) (ToolSource, error) {
+	kind, value, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	switch strings.ToLower(kind) {
+	case "api":
+		return &API{Host: value}, nil
+	case "file":
+		if value == "" {
+			return nil, fmt.Errorf("source %q: missing registry file path", spec)
+		}
+		return &File{Path: value}, nil
+	case "github":
+		if value == "" {
+			value = types.Organization
+		}
+		return &GitHub{Organization: value}, nil
+	default:
+		return nil, fmt.Errorf("source %q: unknown source type %q", spec, kind)
+	}
+}
+
+// Merge returns the tools of all sources, sources listed first taking
+// priority when several provide a tool with the same name. The tools a
+// failing source could still list are kept and its error is returned
+// alongside the merged list.
+func Merge(sources ...ToolSource) ([]types.Tool, error) {
+	var tools []types.Tool
+	var errs []error
+	seen := make(map[string]struct{})
+	for _, source := range sources {
+		sourceTools, err := source.Tools()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+		}
+		for _, tool := range sourceTools {
+			name := strings.ToLower(tool.Name)
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			tools = append(tools, tool)
+		}
+	}
+	return tools, errors.Join(errs...)
+}
@@ -0,0 +1,162 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type staticSource struct {
+	name  string
+	tools []types.Tool
+	err   error
+}
+
+func (s *staticSource) Name() string                 { return s.name }
+func (s *staticSource) Tools() ([]types.Tool, error) { return s.tools, s.err }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want ToolSource
+	}{
+		{"api", &API{}},
+		{"api:https://pdtm.example.com", &API{Host: "https://pdtm.example.com"}},
+		{"file:./tools.yaml", &File{Path: "./tools.yaml"}},
+		{"github", &GitHub{Organization: types.Organization}},
+		{"github:acme", &GitHub{Organization: "acme"}},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.spec)
+		require.NoError(t, err, test.spec)
+		require.Equal(t, test.want, got, test.spec)
+	}
+
+	_, err := Parse("file")
+	require.Error(t, err)
+	_, err = Parse("ftp:example.com")
+	require.Error(t, err)
+}
+
+func TestMergePriority(t *testing.T) {
+	internal := &staticSource{name: "internal", tools: []types.Tool{
+		{Name: "nuclei", Repo: "nuclei-fork", Version: "3.0.0"},
+		{Name: "scanner", Repo: "scanner", Version: "1.0.0"},
+	}}
+	broken := &staticSource{name: "broken", err: errors.New("unreachable")}
+	public := &staticSource{name: "public", tools: []types.Tool{
+		{Name: "Nuclei", Repo: "nuclei", Version: "3.1.0"},
+		{Name: "httpx", Repo: "httpx", Version: "1.6.0"},
+	}}
+
+	tools, err := Merge(internal, broken, public)
+	require.ErrorContains(t, err, "broken: unreachable")
+	require.Equal(t, []types.Tool{
+		{Name: "nuclei", Repo: "nuclei-fork", Version: "3.0.0"},
+		{Name: "scanner", Repo: "scanner", Version: "1.0.0"},
+		{Name: "httpx", Repo: "httpx", Version: "1.6.0"},
+	}, tools)
+}
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "tools.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`tools:
+  - name: scanner
+    owner: acme
+    version: 1.2.0
+    assets:
+      scanner_1.2.0_linux_amd64.zip: "42"
+    # tool lists can not switch verification off or supply digests
+    skip_checksum: true
+    checksums:
+      scanner_1.2.0_linux_amd64.zip: 0000000000000000000000000000000000000000000000000000000000000000
+`), 0644))
+
+	tools, err := (&File{Path: yamlFile}).Tools()
+	require.NoError(t, err)
+	require.Equal(t, []types.Tool{{
+		Name:        "scanner",
+		Owner:       "acme",
+		Repo:        "scanner",
+		Version:     "1.2.0",
+		InstallType: types.Binary,
+		Assets:      map[string]string{"scanner_1.2.0_linux_amd64.zip": "42"},
+	}}, tools)
+
+	jsonFile := filepath.Join(dir, "tools.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`[{"name":"httpx","repo":"httpx","version":"1.6.0","install_type":"go","skip_checksum":true,"checksums":{"httpx.zip":"00"}}]`), 0644))
+	tools, err = (&File{Path: jsonFile}).Tools()
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	require.Equal(t, "httpx", tools[0].Name)
+	require.Equal(t, types.Go, tools[0].InstallType)
+	require.False(t, tools[0].SkipChecksum)
+	require.Nil(t, tools[0].Checksums)
+
+	_, err = (&File{Path: filepath.Join(dir, "missing.yaml")}).Tools()
+	require.Error(t, err)
+}
+
+func TestRegistry(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pdtm", "tools.yaml")
+
+	removed, err := Unregister(file, "ffuf")
+	require.NoError(t, err)
+	require.False(t, removed)
+
+	require.NoError(t, Register(file, types.Tool{Name: "ffuf", Owner: "ffuf", Repo: "ffuf", Version: "2.1.0", InstallType: types.Binary}))
+	require.NoError(t, Register(file, types.Tool{Name: "gau", Owner: "lc", Repo: "gau", InstallType: types.Binary}))
+	require.NoError(t, Register(file, types.Tool{Name: "FFUF", Owner: "ffuf", Repo: "ffuf", InstallType: types.Binary, VersionCommand: "-V"}))
+
+	registered, err := loadEntries(file)
+	require.NoError(t, err)
+	require.Equal(t, []entry{
+		{Name: "FFUF", Owner: "ffuf", Repo: "ffuf", InstallType: types.Binary, VersionCommand: "-V"},
+		{Name: "gau", Owner: "lc", Repo: "gau", InstallType: types.Binary},
+	}, registered.Tools)
+
+	removed, err = Unregister(file, "ffuf")
+	require.NoError(t, err)
+	require.True(t, removed)
+	registered, err = loadEntries(file)
+	require.NoError(t, err)
+	require.Len(t, registered.Tools, 1)
+}
+
+func TestFileSourceReleaseCache(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tools.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`tools:
+  - name: gau
+    owner: lc
+`), 0644))
+	cache := &ReleaseCache{Path: filepath.Join(dir, "releases.json"), TTL: time.Hour}
+	releases := cache.load()
+	cache.put(releases, types.Tool{Owner: "LC", Repo: "gau", Version: "2.2.3", Assets: map[string]string{"gau_2.2.3_linux_amd64.tar.gz": "7"}})
+	require.NoError(t, cache.save(releases))
+
+	// the cached release is used without asking GitHub
+	tools, err := (&File{Path: file, Releases: cache}).Tools()
+	require.NoError(t, err)
+	require.Equal(t, []types.Tool{{
+		Name:        "gau",
+		Owner:       "lc",
+		Repo:        "gau",
+		Version:     "2.2.3",
+		InstallType: types.Binary,
+		Assets:      map[string]string{"gau_2.2.3_linux_amd64.tar.gz": "7"},
+	}}, tools)
+
+	_, ok := (&ReleaseCache{TTL: time.Hour}).get(releases, types.Tool{Owner: "lc", Repo: "other"})
+	require.False(t, ok)
+	_, ok = (&ReleaseCache{TTL: -time.Second}).get(releases, types.Tool{Owner: "lc", Repo: "gau"})
+	require.False(t, ok, "expired releases are resolved again")
+	_, ok = (*ReleaseCache)(nil).get(releases, types.Tool{Owner: "lc", Repo: "gau"})
+	require.False(t, ok)
+}
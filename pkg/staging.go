@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/projectdiscovery/gologger"
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// tmpSuffix marks files written next to their destination before being
+// renamed into place
+const tmpSuffix = ".tmp"
+
+// rename is used to move binaries into place, overridden in tests to inject failures
+var rename = os.Rename
+
+// newStagingDir creates a temporary directory under path where release
+// archives are extracted before the binary is moved into place. Keeping it
+// on the same filesystem as the binary path makes the final rename atomic.
+func newStagingDir(path, toolName string) (string, error) {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(path, fmt.Sprintf(".%s-staging-", toolName))
+}
+
+// removeStagingDir removes the staging directory and everything left in it
+func removeStagingDir(toolName, stagingDir string) {
+	if err := os.RemoveAll(stagingDir); err != nil {
+		gologger.Warning().Msgf("%s: error removing staging directory %s: %s", toolName, stagingDir, err)
+	}
+}
+
+// commitStaged moves the staged binary of tool into its version directory
+// and makes it the active version. The previously active version stays
+// installed and keeps running until the active version is switched, which
+// is atomic. A binary installed directly in path is kept as legacyVersion.
+func commitStaged(stagingDir, path string, tool types.Tool, legacyVersion string) error {
+	stagedPath, exists := ospath.GetExecutablePath(stagingDir, tool.Name)
+	if !exists {
+		return fmt.Errorf(types.ErrNoBinaryInArchive, tool.Name)
+	}
+	dir := ospath.VersionDir(path, tool.Name, tool.Version)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	if err := rename(stagedPath, filepath.Join(dir, filepath.Base(stagedPath))); err != nil {
+		// only removed when nothing was installed there before
+		_ = os.Remove(dir)
+		return err
+	}
+	return activate(path, tool.Name, tool.Version, legacyVersion)
+}
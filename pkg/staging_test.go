@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// localRelease serves release assets from memory instead of GitHub
+type localRelease struct {
+	tool   types.Tool
+	assets map[int][]byte
+}
+
+func newLocalRelease(t *testing.T, name, version, binaryContent string) *localRelease {
+	t.Helper()
+
+	archiveName := fmt.Sprintf("%s_%s_%s.tar.gz", name, version, ospath.CheckOS())
+	archive := tarGzArchive(t, name, binaryContent)
+	sum := sha256.Sum256(archive)
+	checksums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archiveName)
+
+	return &localRelease{
+		tool: types.Tool{
+			Name:    name,
+			Repo:    name,
+			Version: version,
+			Assets: map[string]string{
+				archiveName: "1",
+				fmt.Sprintf("%s_%s_checksums.txt", name, version): "2",
+			},
+		},
+		assets: map[int][]byte{1: archive, 2: []byte(checksums)},
+	}
+}
+
+func (l *localRelease) fetch(tool types.Tool, id int) (io.ReadCloser, int64, error) {
+	data, ok := l.assets[id]
+	if !ok {
+		return nil, 0, fmt.Errorf("asset %d not found", id)
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+func tarGzArchive(t *testing.T, name, content string) []byte {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		name += extIfFound
+	}
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content))}))
+	_, err := tarWriter.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	return buf.Bytes()
+}
+
+func useLocalRelease(t *testing.T, release *localRelease) {
+	t.Helper()
+
+	fetchAsset = release.fetch
+	t.Cleanup(func() { fetchAsset = downloadAsset })
+}
+
+// versionScript returns a binary stand-in that reports the given version
+func versionScript(version string) string {
+	return fmt.Sprintf("#!/bin/sh\necho v%s\n", version)
+}
+
+func readBinary(t *testing.T, path, toolName string) string {
+	t.Helper()
+
+	executablePath, exists := ospath.GetExecutablePath(path, toolName)
+	require.True(t, exists, "binary should be installed")
+	content, err := os.ReadFile(executablePath)
+	require.NoError(t, err)
+	return string(content)
+}
+
+// requireCleanPath ensures no staging directory or temporary file is left behind
+func requireCleanPath(t *testing.T, path string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		require.False(t, strings.Contains(entry.Name(), "-staging-"), "staging directory left behind: %s", entry.Name())
+		require.False(t, strings.HasSuffix(entry.Name(), tmpSuffix), "temporary file left behind: %s", entry.Name())
+	}
+}
+
+func TestStagedInstall(t *testing.T) {
+	pathBin := t.TempDir()
+	release := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	useLocalRelease(t, release)
+
+	require.NoError(t, Install(pathBin, release.tool))
+	require.Equal(t, versionScript("1.1.1"), readBinary(t, pathBin, "dnsx"))
+	requireCleanPath(t, pathBin)
+}
+
+func TestStagedUpdate(t *testing.T) {
+	pathBin := t.TempDir()
+	useLocalRelease(t, newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1")))
+	require.NoError(t, Install(pathBin, newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1")).tool))
+
+	release := newLocalRelease(t, "dnsx", "1.1.2", versionScript("1.1.2"))
+	useLocalRelease(t, release)
+	require.NoError(t, Update(pathBin, release.tool, true))
+	require.Equal(t, versionScript("1.1.2"), readBinary(t, pathBin, "dnsx"))
+	requireCleanPath(t, pathBin)
+}
+
+func TestStagedUpdateRollback(t *testing.T) {
+	oldBinary := versionScript("1.1.1")
+
+	tests := []struct {
+		name   string
+		inject func(t *testing.T, release *localRelease)
+	}{
+		{
+			name: "asset lookup",
+			inject: func(t *testing.T, release *localRelease) {
+				for asset := range release.tool.Assets {
+					if !strings.HasSuffix(asset, "checksums.txt") {
+						delete(release.tool.Assets, asset)
+					}
+				}
+			},
+		},
+		{
+			name: "checksum download",
+			inject: func(t *testing.T, release *localRelease) {
+				delete(release.assets, 2)
+			},
+		},
+		{
+			name: "archive download",
+			inject: func(t *testing.T, release *localRelease) {
+				delete(release.assets, 1)
+			},
+		},
+		{
+			name: "extraction",
+			inject: func(t *testing.T, release *localRelease) {
+				release.assets[1] = []byte("not a gzip archive")
+			},
+		},
+		{
+			name: "checksum verification",
+			inject: func(t *testing.T, release *localRelease) {
+				release.assets[1] = tarGzArchive(t, "dnsx", "tampered")
+			},
+		},
+		{
+			name: "binary missing from archive",
+			inject: func(t *testing.T, release *localRelease) {
+				archive := tarGzArchive(t, "README.md", "docs")
+				sum := sha256.Sum256(archive)
+				release.assets[1] = archive
+				for asset, id := range release.tool.Assets {
+					if id == "1" {
+						release.assets[2] = []byte(hex.EncodeToString(sum[:]) + "  " + asset + "\n")
+					}
+				}
+			},
+		},
+		{
+			name: "swap",
+			inject: func(t *testing.T, release *localRelease) {
+				rename = func(oldpath, newpath string) error {
+					if strings.Contains(oldpath, "-staging-") {
+						return errors.New("injected rename failure")
+					}
+					return os.Rename(oldpath, newpath)
+				}
+				t.Cleanup(func() { rename = os.Rename })
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pathBin := t.TempDir()
+			useLocalRelease(t, newLocalRelease(t, "dnsx", "1.1.1", oldBinary))
+			require.NoError(t, Install(pathBin, newLocalRelease(t, "dnsx", "1.1.1", oldBinary).tool))
+
+			release := newLocalRelease(t, "dnsx", "1.1.2", versionScript("1.1.2"))
+			tt.inject(t, release)
+			useLocalRelease(t, release)
+
+			require.Error(t, Update(pathBin, release.tool, true))
+			require.Equal(t, oldBinary, readBinary(t, pathBin, "dnsx"), "original binary should be restored")
+			requireCleanPath(t, pathBin)
+		})
+	}
+}
+
+func TestStagedInstallFailureLeavesNothing(t *testing.T) {
+	pathBin := t.TempDir()
+	release := newLocalRelease(t, "dnsx", "1.1.1", versionScript("1.1.1"))
+	release.assets[1] = tarGzArchive(t, "dnsx", "tampered")
+	useLocalRelease(t, release)
+
+	err := Install(pathBin, release.tool)
+	var mismatchErr *types.ChecksumMismatchError
+	require.True(t, errors.As(err, &mismatchErr))
+	_, exists := ospath.GetExecutablePath(pathBin, "dnsx")
+	require.False(t, exists, "unverified binary should not be installed")
+	requireCleanPath(t, pathBin)
+}
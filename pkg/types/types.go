@@ -1,25 +1,153 @@
 package types
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 const Organization = "projectdiscovery"
 
 var (
 	ErrIsInstalled = errors.New("already installed")
 	ErrIsUpToDate  = errors.New("already up to date")
+	ErrIsNewer     = errors.New("installed version is newer than latest")
 
-	ErrNoAssetFound = "could not find release asset for your platform (%s/%s)"
-	ErrToolNotFound = "%s: tool not found in path %s: skipping"
+	ErrNoAssetFound      = "could not find release asset for your platform (%s/%s)"
+	ErrToolNotFound      = "%s: tool not found in path %s: skipping"
+	ErrNoChecksumFound   = "could not find checksum for %s in %s"
+	ErrNoChecksumFile    = "release does not publish %s, use -skip-checksum to install it without verification"
+	ErrNoBinaryInArchive = "could not find %s binary in release archive"
 )
 
+// ChecksumMismatchError is returned when a downloaded release asset does not
+// match the sha256 digest published in the release checksums file
+type ChecksumMismatchError struct {
+	Asset    string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected sha256 %s, got %s", e.Asset, e.Expected, e.Actual)
+}
+
+// ChecksumUnavailableError is returned when the checksums file of a release
+// is missing or could not be fetched, so its assets can not be verified
+type ChecksumUnavailableError struct {
+	File string
+	// Err is the error fetching File, nil when the release does not publish it
+	Err error
+}
+
+func (e *ChecksumUnavailableError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf(ErrNoChecksumFile, e.File)
+	}
+	return fmt.Sprintf("could not fetch %s: %s", e.File, e.Err)
+}
+
+func (e *ChecksumUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatusError is returned when a download responds with an unexpected
+// status code
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d %s for %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+}
+
+// Temporary reports whether the request may succeed when retried, i.e. on
+// server errors and rate limiting
+func (e *HTTPStatusError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+// ResumeError is returned when an interrupted download can not be resumed,
+// because the asset changed since the download started or the server
+// resumed at another offset. The download has to start over.
+type ResumeError struct {
+	URL    string
+	Reason string
+}
+
+func (e *ResumeError) Error() string {
+	return fmt.Sprintf("could not resume download of %s: %s", e.URL, e.Reason)
+}
+
 type Tool struct {
-	Name          string            `json:"name"`
+	Name string `json:"name"`
+	// Owner is the GitHub user or organization hosting Repo, projectdiscovery when empty
+	Owner         string            `json:"owner,omitempty" yaml:"owner,omitempty"`
 	Repo          string            `json:"repo"`
 	Version       string            `json:"version"`
 	GoInstallPath string            `json:"go_install_path" yaml:"go_install_path"`
 	Requirements  []ToolRequirement `json:"requirements"`
 	Assets        map[string]string `json:"assets"`
 	InstallType   InstallType       `json:"install_type" yaml:"install_type"`
+	// AssetTemplate names the release asset of a platform using the {name},
+	// {version}, {os} and {arch} placeholders, e.g. "{name}_{version}_{os}_{arch}"
+	AssetTemplate string `json:"asset_template,omitempty" yaml:"asset_template,omitempty"`
+	// OSAliases and ArchAliases map a GOOS or GOARCH to the spellings used in
+	// asset names, e.g. {"darwin": ["apple-darwin"]}
+	OSAliases   map[string][]string `json:"os_aliases,omitempty" yaml:"os_aliases,omitempty"`
+	ArchAliases map[string][]string `json:"arch_aliases,omitempty" yaml:"arch_aliases,omitempty"`
+	// ArchiveFormat is the format of the release assets (zip, tar.gz, tgz,
+	// tar.xz, tar.zst, gz or binary), detected from the extension when empty
+	ArchiveFormat string `json:"archive_format,omitempty" yaml:"archive_format,omitempty"`
+	// VersionCommand is the argument printing the installed version, tried
+	// before the default --version and version commands
+	VersionCommand string `json:"version_command,omitempty" yaml:"version_command,omitempty"`
+	// Files lists the auxiliary files installed from the release archive
+	Files []ToolFile `json:"files,omitempty" yaml:"files,omitempty"`
+	// Checksums holds known sha256 digests by asset name, when set they are
+	// used instead of the release checksums file. It is only set from local
+	// lockfiles and bundles, never from a tool list.
+	Checksums map[string]string `json:"-" yaml:"-"`
+	// SkipChecksum installs the release assets without verification, for
+	// projectdiscovery releases that do not publish a checksums file. It is
+	// only set from local lockfiles, never from a tool list.
+	SkipChecksum bool `json:"-" yaml:"-"`
+	// DownloadURL is a base url serving the release assets as
+	// <download_url>/<asset name>, used instead of GitHub when set
+	DownloadURL string `json:"download_url,omitempty" yaml:"download_url,omitempty"`
+	// Pinned is set when Version is a pinned version rather than the latest release
+	Pinned bool `json:"-" yaml:"-"`
+}
+
+// GetOwner returns the GitHub owner of the tool repository
+func (t Tool) GetOwner() string {
+	if t.Owner == "" {
+		return Organization
+	}
+	return t.Owner
+}
+
+// FileKind is the kind of an auxiliary file, deciding where it is installed
+type FileKind string
+
+const (
+	BashCompletion FileKind = "bash-completion"
+	ZshCompletion  FileKind = "zsh-completion"
+	FishCompletion FileKind = "fish-completion"
+	ManPage        FileKind = "man"
+	Config         FileKind = "config"
+	Doc            FileKind = "doc"
+)
+
+// ToolFile is an auxiliary file shipped in a release archive, such as a
+// shell completion script, a man page or a default config
+type ToolFile struct {
+	// Pattern is a glob matched against the archive path or base name of entries
+	Pattern string   `json:"pattern" yaml:"pattern"`
+	Kind    FileKind `json:"kind" yaml:"kind"`
+	// Name renames the installed file, e.g. _nuclei for zsh completions
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 }
 
 type InstallType string
@@ -29,6 +157,17 @@ const (
 	Go     InstallType = "go"
 )
 
+// ToolStatus describes how an installed tool compares to the latest release
+type ToolStatus string
+
+const (
+	StatusLatest       ToolStatus = "latest"
+	StatusOutdated     ToolStatus = "outdated"
+	StatusNewer        ToolStatus = "newer than latest"
+	StatusNotInstalled ToolStatus = "not installed"
+	StatusNotSupported ToolStatus = "not supported"
+)
+
 type ToolRequirement struct {
 	OS            string                         `json:"os"`
 	Specification []ToolRequirementSpecification `json:"specification"`
@@ -3,8 +3,6 @@ package pkg
 import (
 	"context"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/charmbracelet/glamour"
 	ospath "github.com/projectdiscovery/pdtm/pkg/path"
@@ -17,65 +15,98 @@ import (
 // Update updates a given tool
 func Update(path string, tool types.Tool, disableChangeLog bool) error {
 	if executablePath, exists := ospath.GetExecutablePath(path, tool.Name); exists {
-		if isUpToDate(tool, path) {
-			return types.ErrIsUpToDate
+		if err := checkUpToDate(tool, path); err != nil {
+			return err
 		}
 		gologger.Info().Msgf("updating %s...", tool.Name)
 
-		if len(tool.Assets) == 0 {
+		// tools installed with go install are updated the same way rather
+		// than replaced by a release asset
+		installType := types.Binary
+		if r, _, ok := InstalledReceipt(path, tool.Name); ok {
+			installType = r.InstallType
+		}
+		if installType != types.Go && len(tool.Assets) == 0 {
 			return fmt.Errorf(types.ErrNoAssetFound, tool.Name, executablePath)
 		}
 
-		if err := os.Remove(executablePath); err != nil {
-			return err
+		// a version installed side by side before is switched to directly
+		if _, exists := ospath.VersionExecutablePath(path, tool.Name, tool.Version); exists {
+			if err := switchVersion(path, tool); err != nil {
+				return err
+			}
+			gologger.Info().Msgf("updated %s to installed %s (%s)", tool.Name, tool.Version, versionLabel(tool))
+			return nil
 		}
 
-		version, err := install(tool, path)
+		// install keeps the current version active until the new one is
+		// downloaded, verified and ready to be switched to
+		version := tool.Version
+		var err error
+		if installType == types.Go {
+			err = goInstall(path, tool, tool.Version)
+		} else {
+			version, err = install(tool, path)
+		}
 		if err != nil {
 			return err
 		}
 		if !disableChangeLog {
-			showReleaseNotes(tool.Repo, version)
+			showReleaseNotes(tool, version)
 		}
-		gologger.Info().Msgf("updated %s to %s (%s)", tool.Name, version, au.BrightGreen("latest").String())
+		gologger.Info().Msgf("updated %s to %s (%s)", tool.Name, version, versionLabel(tool))
 		return nil
 	} else {
 		return fmt.Errorf(types.ErrToolNotFound, tool.Name, executablePath)
 	}
 }
 
-func isUpToDate(tool types.Tool, path string) bool {
-	v, err := version.ExtractInstalledVersion(tool, path)
-	return err == nil && strings.EqualFold(tool.Version, v)
+// checkUpToDate returns ErrIsUpToDate when the installed version matches
+// tool.Version and ErrIsNewer when it is a newer (e.g. dev) build, which is
+// not downgraded. Pinned tools are moved to exactly their version.
+func checkUpToDate(tool types.Tool, path string) error {
+	v, err := InstalledVersion(path, tool)
+	if err != nil {
+		return nil
+	}
+	switch version.Status(v, tool.Version) {
+	case types.StatusLatest:
+		return types.ErrIsUpToDate
+	case types.StatusNewer:
+		if !tool.Pinned {
+			return types.ErrIsNewer
+		}
+	}
+	return nil
 }
 
 // showReleaseNotes prints the release body for the version that was actually
 // installed. Fetching by tag (instead of "latest") avoids showing notes from
 // a release the user did not get, e.g. when api.pdtm.sh returns a cached
 // older version. See https://github.com/projectdiscovery/pdtm/issues/435.
-func showReleaseNotes(repo, installedVersion string) {
-	body, err := fetchReleaseBody(repo, installedVersion)
+func showReleaseNotes(tool types.Tool, installedVersion string) {
+	body, err := fetchReleaseBody(tool.GetOwner(), tool.Repo, installedVersion)
 	if err != nil {
-		gologger.Warning().Label("updater").Msgf("could not fetch %s %s release notes: %v", repo, installedVersion, err)
+		gologger.Warning().Label("updater").Msgf("could not fetch %s %s release notes: %v", tool.Repo, installedVersion, err)
 		return
 	}
 	r, err := glamour.NewTermRenderer(glamour.WithAutoStyle())
 	if err != nil {
-		gologger.Error().Msgf("markdown rendering not supported: %v", err)
+		gologger.Error().Msgf("%s: markdown rendering not supported: %v", tool.Name, err)
 	}
 	if rendered, err := r.Render(body); err == nil {
 		body = rendered
 	} else {
-		gologger.Error().Msg(err.Error())
+		gologger.Error().Msgf("%s: %s", tool.Name, err)
 	}
-	gologger.Print().Msgf("%v\n", body)
+	// the header keeps notes apart when several tools are updated concurrently
+	gologger.Print().Msgf("%s %s release notes:\n%v\n", tool.Repo, releaseTag(installedVersion), body)
 }
 
-func fetchReleaseBody(repo, installedVersion string) (string, error) {
-	tag := "v" + strings.TrimPrefix(installedVersion, "v")
-	rel, _, err := GithubClient().Repositories.GetReleaseByTag(context.Background(), types.Organization, repo, tag)
+func fetchReleaseBody(owner, repo, installedVersion string) (string, error) {
+	rel, _, err := GithubClient().Repositories.GetReleaseByTag(context.Background(), owner, repo, releaseTag(installedVersion))
 	if err != nil {
-		return "", err
+		return "", githubError(err)
 	}
 	return rel.GetBody(), nil
 }
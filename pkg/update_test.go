@@ -3,6 +3,7 @@ package pkg
 import (
 	"testing"
 
+	"github.com/projectdiscovery/pdtm/pkg/types"
 	"github.com/stretchr/testify/require"
 )
 
@@ -10,7 +11,7 @@ import (
 // supplied version, not whatever GitHub currently considers "latest".
 // Regression coverage for https://github.com/projectdiscovery/pdtm/issues/435.
 func TestFetchReleaseBody_PinsToVersion(t *testing.T) {
-	body, err := fetchReleaseBody("dnsx", "1.1.1")
+	body, err := fetchReleaseBody(types.Organization, "dnsx", "1.1.1")
 	require.NoError(t, err)
 	require.NotEmpty(t, body, "release body for dnsx v1.1.1 should be non-empty")
 }
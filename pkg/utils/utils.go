@@ -9,13 +9,18 @@ import (
 	"strings"
 
 	"github.com/logrusorgru/aurora/v4"
-	"github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg"
+	"github.com/projectdiscovery/pdtm/pkg/asset"
+	"github.com/projectdiscovery/pdtm/pkg/httpclient"
 	"github.com/projectdiscovery/pdtm/pkg/types"
 	"github.com/projectdiscovery/pdtm/pkg/version"
 	updateutils "github.com/projectdiscovery/utils/update"
 )
 
-var host = getEnv("PDTM_SERVER", "https://api.pdtm.sh")
+// DefaultHost is the pdtm api used when PDTM_SERVER is not set
+const DefaultHost = "https://api.pdtm.sh"
+
+var host = getEnv("PDTM_SERVER", DefaultHost)
 
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -28,13 +33,19 @@ func getEnv(key, defaultValue string) string {
 // configure aurora for logging
 var au = aurora.New(aurora.WithColors(true))
 
+// FetchToolList fetches the tool list from the pdtm api set by PDTM_SERVER
 func FetchToolList() ([]types.Tool, error) {
+	return FetchToolListFrom(host)
+}
+
+// FetchToolListFrom fetches the tool list from the pdtm api served at apiHost
+func FetchToolListFrom(apiHost string) ([]types.Tool, error) {
 	tools := make([]types.Tool, 0)
 
 	// Create the request URL with query parameters
-	reqURL := fmt.Sprintf("%s/api/v1/tools/?%s", host, updateutils.GetpdtmParams(""))
+	reqURL := fmt.Sprintf("%s/api/v1/tools/?%s", strings.TrimSuffix(apiHost, "/"), updateutils.GetpdtmParams(""))
 
-	resp, err := http.Get(reqURL)
+	resp, err := httpclient.Client().Get(reqURL)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +74,7 @@ func fetchTool(toolName string) (types.Tool, error) {
 	var tool types.Tool
 	// Create the request URL to get tool
 	reqURL := fmt.Sprintf("%s/api/v1/tools/%s?%s", host, toolName, updateutils.GetpdtmParams(""))
-	resp, err := http.Get(reqURL)
+	resp, err := httpclient.Client().Get(reqURL)
 	if err != nil {
 		return tool, err
 	}
@@ -113,40 +124,44 @@ func Contains(s []types.Tool, toolName string) (int, bool) {
 	return -1, false
 }
 
+// InstalledVersion returns the colored installation status of tool for display
 func InstalledVersion(tool types.Tool, basePath string, au *aurora.Aurora) string {
-	var msg string
-
-	installedVersion, err := version.ExtractInstalledVersion(tool, basePath)
-	if err != nil {
-		osAvailable := isOsAvailable(tool)
-		if !osAvailable {
-			msg = fmt.Sprintf("(%s)", au.Gray(10, "not supported").String())
-		} else {
-			msg = fmt.Sprintf("(%s)", au.BrightYellow("not installed").String())
-		}
+	installedVersion, status := InstalledStatus(tool, basePath)
+	switch status {
+	case types.StatusNotSupported:
+		return fmt.Sprintf("(%s)", au.Gray(10, status).String())
+	case types.StatusNotInstalled:
+		return fmt.Sprintf("(%s)", au.BrightYellow(status).String())
+	case types.StatusLatest:
+		return fmt.Sprintf("(%s) (%s)", au.BrightGreen(status).String(), au.BrightGreen(tool.Version).String())
+	case types.StatusNewer:
+		return fmt.Sprintf("(%s) (%s) ⬅ (%s)",
+			au.BrightCyan(status).String(),
+			au.BrightCyan(installedVersion).String(),
+			au.BrightGreen(tool.Version).String())
+	default:
+		return fmt.Sprintf("(%s) (%s) ➡ (%s)",
+			au.Red(status).String(),
+			au.Red(installedVersion).String(),
+			au.BrightGreen(tool.Version).String())
 	}
+}
 
-	if installedVersion != "" {
-		if strings.Contains(tool.Version, installedVersion) {
-			msg = fmt.Sprintf("(%s) (%s)", au.BrightGreen("latest").String(), au.BrightGreen(tool.Version).String())
-		} else {
-			msg = fmt.Sprintf("(%s) (%s) ➡ (%s)",
-				au.Red("outdated").String(),
-				au.Red(installedVersion).String(),
-				au.BrightGreen(tool.Version).String())
+// InstalledStatus returns the installed version of tool and how it compares
+// to the latest release. The version is read from the install receipt when
+// there is one.
+func InstalledStatus(tool types.Tool, basePath string) (string, types.ToolStatus) {
+	installedVersion, err := pkg.InstalledVersion(basePath, tool)
+	if err != nil || installedVersion == "" {
+		if !isOsAvailable(tool) {
+			return "", types.StatusNotSupported
 		}
+		return "", types.StatusNotInstalled
 	}
-
-	return msg
+	return installedVersion, version.Status(installedVersion, tool.Version)
 }
 
 func isOsAvailable(tool types.Tool) bool {
-	osData := path.CheckOS()
-	for asset := range tool.Assets {
-		expectedAssetPrefix := tool.Name + "_" + tool.Version + "_" + osData
-		if strings.Contains(asset, expectedAssetPrefix) {
-			return true
-		}
-	}
-	return false
+	_, ok := asset.Find(tool)
+	return ok
 }
@@ -0,0 +1,63 @@
+package version
+
+import (
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+)
+
+// Parse parses a semantic version with an optional v prefix, pre-release
+// tag and build metadata
+func Parse(v string) (*semver.Version, error) {
+	return semver.NewVersion(strings.TrimSpace(v))
+}
+
+// Compare compares two versions following semantic versioning precedence,
+// returning -1, 0 or +1. Build metadata is ignored and pre-releases sort
+// before the release they precede. Versions that cannot be parsed are only
+// considered equal when they are the same string.
+func Compare(a, b string) int {
+	va, errA := Parse(a)
+	vb, errB := Parse(b)
+	if errA != nil || errB != nil {
+		a, b = normalize(a), normalize(b)
+		switch {
+		case a == b:
+			return 0
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return va.Compare(vb)
+}
+
+// Equal reports whether two versions have the same precedence
+func Equal(a, b string) bool {
+	return Compare(a, b) == 0
+}
+
+// Status returns how the installed version compares to the latest one
+func Status(installed, latest string) types.ToolStatus {
+	switch cmp := Compare(installed, latest); {
+	case cmp == 0:
+		return types.StatusLatest
+	case cmp > 0 && isComparable(installed, latest):
+		return types.StatusNewer
+	default:
+		return types.StatusOutdated
+	}
+}
+
+// isComparable reports whether both versions are valid semantic versions
+func isComparable(a, b string) bool {
+	_, errA := Parse(a)
+	_, errB := Parse(b)
+	return errA == nil && errB == nil
+}
+
+func normalize(v string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(v), "v"))
+}
@@ -0,0 +1,48 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.1", "v1.2.10", -1},
+		{"v1.2.10", "1.2.1", 1},
+		{"1.2.3", "v1.2.3", 0},
+		{"1.2.3+build.5", "1.2.3", 0},
+		{"3.1.0-rc.1", "3.1.0", -1},
+		{"3.1.0-rc.2", "3.1.0-rc.10", -1},
+		{"3.1.0-alpha", "3.1.0-beta", -1},
+		{"3.2.0-dev", "3.1.0", 1},
+		{"2.0.0", "10.0.0", -1},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, Compare(tt.a, tt.b), "Compare(%q, %q)", tt.a, tt.b)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	require.Equal(t, types.StatusLatest, Status("1.2.10", "v1.2.10"))
+	require.Equal(t, types.StatusOutdated, Status("1.2.1", "v1.2.10"), "prefix match is not latest")
+	require.Equal(t, types.StatusOutdated, Status("3.1.0-rc.1", "3.1.0"))
+	require.Equal(t, types.StatusNewer, Status("3.2.0-dev", "3.1.0"))
+	require.Equal(t, types.StatusOutdated, Status("unknown", "3.1.0"))
+}
+
+func TestRegexVersionNumber(t *testing.T) {
+	tests := map[string]string{
+		"nuclei engine version: v3.1.0":        "v3.1.0",
+		"current version v3.2.0-dev":           "v3.2.0-dev",
+		"httpx 1.6.0+build.1 (latest)":         " 1.6.0+build.1",
+		"projectdiscovery.io\n\nversion 2.0.1": " 2.0.1",
+	}
+	for output, want := range tests {
+		require.Equal(t, want, RegexVersionNumber.FindString(output), output)
+	}
+}
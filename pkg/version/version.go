@@ -4,22 +4,28 @@ import (
 	"bytes"
 	"errors"
 	"os/exec"
-	"path/filepath"
 	"regexp"
 	"strings"
 
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
 	"github.com/projectdiscovery/pdtm/pkg/types"
 )
 
 var (
-	RegexVersionNumber = regexp.MustCompile(`(?m)[v\s](\d+\.\d+\.\d+)`)
-	versionCommands    = []string{"--version", "version"}
+	RegexVersionNumber = regexp.MustCompile(`(?m)[v\s](\d+\.\d+\.\d+(?:-[0-9a-z.-]+)?(?:\+[0-9a-z.-]+)?)`)
+	versionCommands    = []string{"--version", "version", "-version"}
 )
 
+// ExtractInstalledVersion runs the binary of tool installed in basePath, the
+// active version for tools installed side by side, to read its version
 func ExtractInstalledVersion(tool types.Tool, basePath string) (string, error) {
-	toolPath := filepath.Join(basePath, tool.Name)
+	toolPath, _ := ospath.GetExecutablePath(basePath, tool.Name)
 
-	for _, versionCmd := range versionCommands {
+	commands := versionCommands
+	if tool.VersionCommand != "" {
+		commands = append([]string{tool.VersionCommand}, versionCommands...)
+	}
+	for _, versionCmd := range commands {
 		if version, err := tryVersionCommand(toolPath, versionCmd); err == nil {
 			return version, nil
 		}
@@ -0,0 +1,399 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/version"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+const (
+	// DefaultRetention is the number of inactive versions kept per tool
+	DefaultRetention = 3
+	// heldFile marks a version selected with Use, held versions are never pruned
+	heldFile = ".pdtm-held"
+	// installFile records how a version was installed, so that switching
+	// back to it restores the asset and checksum in the receipt
+	installFile = ".pdtm-install"
+)
+
+var (
+	retention      = DefaultRetention
+	retentionMutex sync.RWMutex
+)
+
+// SetRetention sets the number of inactive versions kept per tool when
+// another version is activated, 0 removes replaced versions right away
+func SetRetention(keep int) {
+	retentionMutex.Lock()
+	defer retentionMutex.Unlock()
+	retention = max(keep, 0)
+}
+
+func currentRetention() int {
+	retentionMutex.RLock()
+	defer retentionMutex.RUnlock()
+	return retention
+}
+
+// ToolVersion is a version of a tool installed side by side
+type ToolVersion struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	// Active is set for the version run by the shim outside of directories
+	// with a .pdtm-version file
+	Active bool `json:"active"`
+	// Held is set for versions selected with Use
+	Held bool `json:"held"`
+}
+
+// Versions returns the versions of toolName installed in path, newest first
+func Versions(path, toolName string) ([]ToolVersion, error) {
+	entries, err := os.ReadDir(ospath.ToolVersionsDir(path, toolName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	active, _ := ospath.ActiveVersion(path, toolName)
+	var versions []ToolVersion
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		binaryPath, exists := ospath.VersionExecutablePath(path, toolName, entry.Name())
+		if !exists {
+			continue
+		}
+		info, err := os.Stat(binaryPath)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, ToolVersion{
+			Version: entry.Name(),
+			Path:    binaryPath,
+			Size:    info.Size(),
+			Active:  entry.Name() == active,
+			Held:    fileutil.FileExists(filepath.Join(filepath.Dir(binaryPath), heldFile)),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return version.Compare(versions[i].Version, versions[j].Version) > 0
+	})
+	return versions, nil
+}
+
+// VersionedTools returns the names of the tools installed side by side in path
+func VersionedTools(path string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(path, ospath.VersionsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var toolNames []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			toolNames = append(toolNames, entry.Name())
+		}
+	}
+	return toolNames, nil
+}
+
+// activate makes the installed version v the active version of toolName,
+// writes its shim and prunes the inactive versions to the retention. A
+// binary installed before versions were kept side by side is moved into the
+// versions directory as legacyVersion, or removed when it is unknown.
+func activate(path, toolName, v, legacyVersion string) error {
+	if _, ok := ospath.ActiveVersion(path, toolName); !ok {
+		if err := migrateLegacy(path, toolName, legacyVersion); err != nil {
+			return err
+		}
+	}
+	activeFile := filepath.Join(ospath.ToolVersionsDir(path, toolName), ospath.ActiveFile)
+	if err := writeFileAtomic(activeFile, []byte(strings.TrimPrefix(v, "v")+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := writeShim(path, toolName); err != nil {
+		return err
+	}
+	if _, err := Prune(path, toolName, currentRetention()); err != nil {
+		gologger.Warning().Msgf("%s: could not prune inactive versions: %s", toolName, err)
+	}
+	return nil
+}
+
+// migrateLegacy moves the binary of toolName installed directly in path
+// into the versions directory
+func migrateLegacy(path, toolName, legacyVersion string) error {
+	legacyPath, exists := ospath.GetEntryPath(path, toolName)
+	if !exists {
+		return nil
+	}
+	if legacyVersion == "" {
+		gologger.Verbose().Msgf("%s: installed binary has an unknown version, not keeping it", toolName)
+		return os.Remove(legacyPath)
+	}
+	if _, exists := ospath.VersionExecutablePath(path, toolName, legacyVersion); exists {
+		return os.Remove(legacyPath)
+	}
+	dir := ospath.VersionDir(path, toolName, legacyVersion)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	return rename(legacyPath, filepath.Join(dir, filepath.Base(legacyPath)))
+}
+
+// legacyVersion returns the version of tool when it is installed directly
+// in path instead of side by side, empty otherwise or when it is unknown
+func legacyVersion(path string, tool types.Tool) string {
+	if _, ok := ospath.ActiveVersion(path, tool.Name); ok {
+		return ""
+	}
+	if _, exists := ospath.GetEntryPath(path, tool.Name); !exists {
+		return ""
+	}
+	v, err := InstalledVersion(path, tool)
+	if err != nil {
+		return ""
+	}
+	return v
+}
+
+// switchVersion activates the installed version tool.Version of tool and
+// records it in the receipts
+func switchVersion(path string, tool types.Tool) error {
+	previous, _, hasReceipt := InstalledReceipt(path, tool.Name)
+	if err := activate(path, tool.Name, tool.Version, legacyVersion(path, tool)); err != nil {
+		return err
+	}
+	installed, ok := readVersionInstall(path, tool.Name, tool.Version)
+	switch {
+	case ok:
+	case hasReceipt && version.Equal(previous.Version, tool.Version):
+		installed = versionInstall{InstallType: previous.InstallType, Asset: previous.Asset, AssetChecksum: previous.AssetChecksum}
+	case hasReceipt:
+		installed.InstallType = previous.InstallType
+	default:
+		installed.InstallType = types.Binary
+	}
+	recordReceipt(path, tool, installed.InstallType, installed.Asset, installed.AssetChecksum, previous.Files)
+	return nil
+}
+
+// versionInstall is how a version installed side by side was installed
+type versionInstall struct {
+	InstallType   types.InstallType `json:"install_type"`
+	Asset         string            `json:"asset,omitempty"`
+	AssetChecksum string            `json:"asset_checksum,omitempty"`
+}
+
+// writeVersionInstall records how version of toolName installed in path was
+// installed next to its binary
+func writeVersionInstall(path, toolName, version string, installed versionInstall) error {
+	data, err := json.Marshal(installed)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(ospath.VersionDir(path, toolName, version), installFile), data, 0644)
+}
+
+// readVersionInstall returns how version of toolName installed in path was
+// installed, false for versions installed before it was recorded
+func readVersionInstall(path, toolName, version string) (versionInstall, bool) {
+	var installed versionInstall
+	data, err := os.ReadFile(filepath.Join(ospath.VersionDir(path, toolName, version), installFile))
+	if err != nil || json.Unmarshal(data, &installed) != nil {
+		return versionInstall{}, false
+	}
+	return installed, true
+}
+
+// Prune removes all but the newest keep inactive versions of toolName
+// installed in path and returns the number of bytes reclaimed. Versions
+// held with Use are never removed.
+func Prune(path, toolName string, keep int) (int64, error) {
+	versions, err := Versions(path, toolName)
+	if err != nil {
+		return 0, err
+	}
+	var reclaimed int64
+	for _, tv := range versions {
+		if tv.Active || tv.Held {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		if err := os.RemoveAll(filepath.Dir(tv.Path)); err != nil {
+			return reclaimed, err
+		}
+		reclaimed += tv.Size
+	}
+	return reclaimed, nil
+}
+
+// RemoveVersion removes the inactive version v of tool installed in path
+func RemoveVersion(path string, tool types.Tool, v string) error {
+	if active, ok := ospath.ActiveVersion(path, tool.Name); ok && version.Equal(active, v) {
+		return fmt.Errorf("%s %s is the active version, use another version before removing it", tool.Name, active)
+	}
+	binaryPath, exists := ospath.VersionExecutablePath(path, tool.Name, v)
+	if !exists {
+		return fmt.Errorf("%s %s is not installed", tool.Name, v)
+	}
+	gologger.Info().Msgf("removing %s %s...", tool.Name, v)
+	if err := os.RemoveAll(filepath.Dir(binaryPath)); err != nil {
+		return err
+	}
+	gologger.Info().Msgf("removed %s %s", tool.Name, v)
+	return nil
+}
+
+// removeVersions removes all versions of toolName installed side by side
+func removeVersions(path, toolName string) error {
+	if err := os.RemoveAll(ospath.ToolVersionsDir(path, toolName)); err != nil {
+		return err
+	}
+	// the versions directory goes with the last tool
+	_ = os.Remove(filepath.Join(path, ospath.VersionsDir))
+	return nil
+}
+
+// Use makes tool.Version the active version of tool, installing it side by
+// side when missing, and holds it so it is never pruned
+func Use(path string, tool types.Tool) error {
+	v := strings.TrimPrefix(tool.Version, "v")
+	if _, exists := ospath.VersionExecutablePath(path, tool.Name, v); exists {
+		if err := switchVersion(path, tool); err != nil {
+			return err
+		}
+	} else {
+		gologger.Info().Msgf("installing %s %s...", tool.Name, v)
+		if _, err := install(tool, path); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(ospath.VersionDir(path, tool.Name, v), heldFile), nil, 0644); err != nil {
+		gologger.Warning().Msgf("%s: could not hold %s: %s", tool.Name, v, err)
+	}
+	gologger.Info().Msgf("using %s %s", tool.Name, v)
+	return nil
+}
+
+// Rollback switches tool installed in path back to another installed
+// version and returns it. Without a version the newest version older than
+// the active one is restored. The replaced version stays installed, so a
+// rollback can be undone with another one.
+func Rollback(path string, tool types.Tool, v string) (string, error) {
+	executablePath, exists := ospath.GetExecutablePath(path, tool.Name)
+	if !exists {
+		return "", fmt.Errorf(types.ErrToolNotFound, tool.Name, executablePath)
+	}
+	installed, err := InstalledVersion(path, tool)
+	if err != nil {
+		gologger.Verbose().Msgf("%s: could not read installed version: %s", tool.Name, err)
+		installed = ""
+	}
+	versions, err := Versions(path, tool.Name)
+	if err != nil {
+		return "", err
+	}
+	target, err := rollbackTarget(tool.Name, versions, installed, v)
+	if err != nil {
+		return "", err
+	}
+	tool.Version = target.Version
+	if err := switchVersion(path, tool); err != nil {
+		return "", err
+	}
+	return target.Version, nil
+}
+
+// rollbackTarget selects the installed version to roll back to
+func rollbackTarget(toolName string, versions []ToolVersion, installed, v string) (ToolVersion, error) {
+	var inactive []ToolVersion
+	for _, tv := range versions {
+		if !tv.Active {
+			inactive = append(inactive, tv)
+		}
+	}
+	if v != "" {
+		if installed != "" && version.Equal(v, installed) {
+			return ToolVersion{}, types.ErrIsUpToDate
+		}
+		for _, tv := range inactive {
+			if version.Equal(tv.Version, v) {
+				return tv, nil
+			}
+		}
+		return ToolVersion{}, fmt.Errorf("version %s of %s is not installed (other versions: %s)", v, toolName, formatVersions(inactive))
+	}
+	for _, tv := range inactive {
+		if installed == "" || version.Compare(tv.Version, installed) < 0 {
+			return tv, nil
+		}
+	}
+	return ToolVersion{}, fmt.Errorf("no version of %s older than %s is installed (other versions: %s)", toolName, installed, formatVersions(inactive))
+}
+
+func formatVersions(versions []ToolVersion) string {
+	if len(versions) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(versions))
+	for _, tv := range versions {
+		names = append(names, tv.Version)
+	}
+	return strings.Join(names, ", ")
+}
+
+// LocalVersion returns the version of toolName selected by the nearest
+// .pdtm-version file in dir or its parents, and that file
+func LocalVersion(dir, toolName string) (string, string, bool) {
+	for {
+		file := filepath.Join(dir, LocalVersionFile)
+		if v, ok := readLocalVersion(file, toolName); ok {
+			return v, file, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// readLocalVersion returns the version of toolName listed in file
+func readLocalVersion(file, toolName string) (string, bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", false
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == toolName {
+			return strings.TrimPrefix(fields[1], "v"), true
+		}
+	}
+	return "", false
+}
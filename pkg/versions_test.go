@@ -0,0 +1,277 @@
+package pkg
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	ospath "github.com/projectdiscovery/pdtm/pkg/path"
+	"github.com/projectdiscovery/pdtm/pkg/types"
+	"github.com/projectdiscovery/pdtm/pkg/version"
+	"github.com/stretchr/testify/require"
+)
+
+// useRetention keeps the given number of inactive versions for the test
+func useRetention(t *testing.T, keep int) {
+	t.Helper()
+
+	previous := currentRetention()
+	SetRetention(keep)
+	t.Cleanup(func() { SetRetention(previous) })
+}
+
+// installVersions installs dnsx, or updates it when installed, through the
+// given versions
+func installVersions(t *testing.T, pathBin string, versions ...string) types.Tool {
+	t.Helper()
+
+	var tool types.Tool
+	for _, v := range versions {
+		release := newLocalRelease(t, "dnsx", v, versionScript(v))
+		useLocalRelease(t, release)
+		tool = release.tool
+		if _, exists := ospath.GetExecutablePath(pathBin, tool.Name); !exists {
+			require.NoError(t, Install(pathBin, tool))
+		} else {
+			require.NoError(t, Update(pathBin, tool, true))
+		}
+	}
+	return tool
+}
+
+// inactiveVersions returns the installed versions of dnsx besides the active one
+func inactiveVersions(t *testing.T, pathBin string) []string {
+	t.Helper()
+
+	versions, err := Versions(pathBin, "dnsx")
+	require.NoError(t, err)
+	inactive := []string{}
+	for _, tv := range versions {
+		if !tv.Active {
+			inactive = append(inactive, tv.Version)
+		}
+	}
+	return inactive
+}
+
+func TestUpdateKeepsVersions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version scripts need a shell")
+	}
+	useReceipts(t)
+	useRetention(t, 2)
+	pathBin := t.TempDir()
+
+	installVersions(t, pathBin, "1.0.0", "1.1.0", "1.2.0")
+	require.Equal(t, []string{"1.1.0", "1.0.0"}, inactiveVersions(t, pathBin))
+
+	installVersions(t, pathBin, "1.3.0")
+	require.Equal(t, []string{"1.2.0", "1.1.0"}, inactiveVersions(t, pathBin))
+	require.Equal(t, versionScript("1.3.0"), readBinary(t, pathBin, "dnsx"))
+	requireCleanPath(t, pathBin)
+
+	reclaimed, err := Prune(pathBin, "dnsx", 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(versionScript("1.1.0"))), reclaimed)
+	require.Equal(t, []string{"1.2.0"}, inactiveVersions(t, pathBin))
+
+	require.NoError(t, Remove(pathBin, types.Tool{Name: "dnsx"}))
+	require.NoDirExists(t, filepath.Join(pathBin, ospath.VersionsDir))
+	_, exists := ospath.GetEntryPath(pathBin, "dnsx")
+	require.False(t, exists)
+}
+
+func TestUpdateWithoutRetention(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version scripts need a shell")
+	}
+	useReceipts(t)
+	useRetention(t, 0)
+	pathBin := t.TempDir()
+
+	installVersions(t, pathBin, "1.0.0", "1.1.0")
+	require.Empty(t, inactiveVersions(t, pathBin))
+	requireCleanPath(t, pathBin)
+}
+
+func TestUpdateSwitchesToInstalledVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version scripts need a shell")
+	}
+	useReceipts(t)
+	pathBin := t.TempDir()
+	installVersions(t, pathBin, "1.0.0", "1.1.0")
+
+	// a pinned downgrade to an installed version needs no download
+	release := newLocalRelease(t, "dnsx", "1.0.0", versionScript("1.0.0"))
+	release.assets = nil
+	useLocalRelease(t, release)
+	release.tool.Pinned = true
+	require.NoError(t, Update(pathBin, release.tool, true))
+	require.Equal(t, versionScript("1.0.0"), readBinary(t, pathBin, "dnsx"))
+	require.Equal(t, []string{"1.1.0"}, inactiveVersions(t, pathBin))
+}
+
+func TestRollback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version scripts need a shell")
+	}
+	useReceipts(t)
+	useRetention(t, 3)
+	pathBin := t.TempDir()
+	tool := installVersions(t, pathBin, "1.0.0", "1.1.0", "1.2.0")
+
+	restored, err := Rollback(pathBin, tool, "")
+	require.NoError(t, err)
+	require.Equal(t, "1.1.0", restored)
+	require.Equal(t, versionScript("1.1.0"), readBinary(t, pathBin, "dnsx"))
+	require.Equal(t, []string{"1.2.0", "1.0.0"}, inactiveVersions(t, pathBin))
+	installed, err := InstalledVersion(pathBin, tool)
+	require.NoError(t, err)
+	require.Equal(t, "1.1.0", installed)
+
+	// rolling back again goes further back
+	restored, err = Rollback(pathBin, tool, "")
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0", restored)
+	_, err = Rollback(pathBin, tool, "")
+	require.Error(t, err)
+
+	restored, err = Rollback(pathBin, tool, "v1.2.0")
+	require.NoError(t, err)
+	require.Equal(t, "1.2.0", restored)
+	require.Equal(t, versionScript("1.2.0"), readBinary(t, pathBin, "dnsx"))
+	require.Equal(t, []string{"1.1.0", "1.0.0"}, inactiveVersions(t, pathBin))
+
+	_, err = Rollback(pathBin, tool, "1.2.0")
+	require.ErrorIs(t, err, types.ErrIsUpToDate)
+	_, err = Rollback(pathBin, tool, "0.9.0")
+	require.Error(t, err)
+	require.Equal(t, versionScript("1.2.0"), readBinary(t, pathBin, "dnsx"))
+	requireCleanPath(t, pathBin)
+}
+
+func TestSwitchVersionKeepsAsset(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version scripts need a shell")
+	}
+	store := useReceipts(t)
+	useRetention(t, 3)
+	pathBin := t.TempDir()
+	installVersions(t, pathBin, "1.0.0")
+	old, ok := store.Get(pathBin, "dnsx")
+	require.True(t, ok)
+	require.NotEmpty(t, old.Asset)
+	require.NotEmpty(t, old.AssetChecksum)
+	tool := installVersions(t, pathBin, "1.1.0")
+	latest, ok := store.Get(pathBin, "dnsx")
+	require.True(t, ok)
+	require.NotEqual(t, old.AssetChecksum, latest.AssetChecksum)
+
+	_, err := Rollback(pathBin, tool, "1.0.0")
+	require.NoError(t, err)
+	r, ok := store.Get(pathBin, "dnsx")
+	require.True(t, ok)
+	require.Equal(t, "1.0.0", r.Version)
+	require.Equal(t, old.Asset, r.Asset)
+	require.Equal(t, old.AssetChecksum, r.AssetChecksum)
+
+	tool.Version = "1.1.0"
+	require.NoError(t, Use(pathBin, tool))
+	r, ok = store.Get(pathBin, "dnsx")
+	require.True(t, ok)
+	require.Equal(t, "1.1.0", r.Version)
+	require.Equal(t, latest.Asset, r.Asset)
+	require.Equal(t, latest.AssetChecksum, r.AssetChecksum)
+}
+
+func TestUseHoldsVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version scripts need a shell")
+	}
+	useReceipts(t)
+	useRetention(t, 0)
+	pathBin := t.TempDir()
+	installVersions(t, pathBin, "3.0.0")
+
+	legacy := newLocalRelease(t, "dnsx", "2.9.0", versionScript("2.9.0"))
+	useLocalRelease(t, legacy)
+	require.NoError(t, Use(pathBin, legacy.tool))
+	require.Equal(t, versionScript("2.9.0"), readBinary(t, pathBin, "dnsx"))
+
+	// held versions survive updates without retention
+	installVersions(t, pathBin, "3.0.0", "3.1.0")
+	require.Equal(t, versionScript("3.1.0"), readBinary(t, pathBin, "dnsx"))
+	require.Equal(t, []string{"2.9.0"}, inactiveVersions(t, pathBin))
+	reclaimed, err := Prune(pathBin, "dnsx", 0)
+	require.NoError(t, err)
+	require.Zero(t, reclaimed)
+
+	tool := types.Tool{Name: "dnsx"}
+	require.Error(t, RemoveVersion(pathBin, tool, "3.1.0"))
+	require.Error(t, RemoveVersion(pathBin, tool, "1.0.0"))
+	require.NoError(t, RemoveVersion(pathBin, tool, "2.9.0"))
+	require.Empty(t, inactiveVersions(t, pathBin))
+}
+
+func TestLegacyBinaryMigration(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version scripts need a shell")
+	}
+	pathBin := t.TempDir()
+	// a binary installed before versions were kept side by side
+	require.NoError(t, os.WriteFile(filepath.Join(pathBin, "dnsx"), []byte(versionScript("0.9.0")), 0755))
+
+	tool := installVersions(t, pathBin, "1.0.0")
+	require.Equal(t, []string{"0.9.0"}, inactiveVersions(t, pathBin))
+	executablePath, exists := ospath.GetExecutablePath(pathBin, "dnsx")
+	require.True(t, exists)
+	require.Equal(t, filepath.Join(ospath.VersionDir(pathBin, "dnsx", "1.0.0"), "dnsx"), executablePath)
+	installed, err := version.ExtractInstalledVersion(tool, pathBin)
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0", installed)
+}
+
+// runShim runs the shim of dnsx in dir and returns its output
+func runShim(t *testing.T, pathBin, dir string) (string, error) {
+	t.Helper()
+
+	cmd := exec.Command(filepath.Join(pathBin, "dnsx"), "-version")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PWD="+dir)
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+func TestShimLocalVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip(".pdtm-version files are not read on windows")
+	}
+	pathBin := t.TempDir()
+	installVersions(t, pathBin, "2.9.0", "3.1.0")
+
+	project := t.TempDir()
+	nested := filepath.Join(project, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, os.ModePerm))
+
+	output, err := runShim(t, pathBin, nested)
+	require.NoError(t, err)
+	require.Equal(t, "v3.1.0", output)
+
+	require.NoError(t, os.WriteFile(filepath.Join(project, LocalVersionFile), []byte("# legacy workflows\nnuclei 2.0.0\ndnsx v2.9.0\n"), 0644))
+	output, err = runShim(t, pathBin, nested)
+	require.NoError(t, err)
+	require.Equal(t, "v2.9.0", output)
+	v, file, ok := LocalVersion(nested, "dnsx")
+	require.True(t, ok)
+	require.Equal(t, "2.9.0", v)
+	require.Equal(t, filepath.Join(project, LocalVersionFile), file)
+
+	require.NoError(t, os.WriteFile(filepath.Join(nested, LocalVersionFile), []byte("dnsx 1.0.0\n"), 0644))
+	output, err = runShim(t, pathBin, nested)
+	require.Error(t, err)
+	require.Contains(t, output, "dnsx 1.0.0 is not installed")
+}